@@ -0,0 +1,241 @@
+package gosh
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/viant/gosh/runner"
+)
+
+const (
+	osReleaseCmd    = "cat /etc/os-release 2>/dev/null || cat /usr/lib/os-release"
+	anyReleaseCmd   = "cat /etc/*-release"
+	lsbReleaseCmd   = "lsb_release -a"
+	swVersCmd       = "sw_vers"
+	kernelCmd       = "uname -r"
+	dmiVersionCmd   = "cat /sys/class/dmi/id/product_version"
+	appleVersionCmd = "sysctl -n hw.model"
+)
+
+// releaseExpr matches free form release files, e.g. CentOS release 6.10 (Final)
+var releaseExpr = regexp.MustCompile(`^(.+?)\s+(?:Linux\s+)?release\s+([\d.]+)\s*(?:\((.+)\))?`)
+
+// macOSCodenames maps macOS major version to its name
+var macOSCodenames = map[string]string{
+	"10.13": "high sierra",
+	"10.14": "mojave",
+	"10.15": "catalina",
+	"11":    "big sur",
+	"12":    "monterey",
+	"13":    "ventura",
+	"14":    "sonoma",
+	"15":    "sequoia",
+	"26":    "tahoe",
+}
+
+// detector collects operating system and hardware details
+type detector struct {
+	runner runner.Runner
+	err    error
+}
+
+// output returns trimmed stdout of a successful command
+func (d *detector) output(ctx context.Context, command string) (string, bool) {
+	result, err := runner.RunResult(ctx, d.runner, command)
+	if err != nil {
+		if d.err == nil && !errors.Is(err, runner.ErrNotFound) {
+			d.err = err
+		}
+		return "", false
+	}
+	if result.ExitCode != 0 {
+		return "", false
+	}
+	output := strings.TrimSpace(result.Stdout)
+	return output, output != ""
+}
+
+func (d *detector) detect(ctx context.Context, osInfo *OSInfo, hwInfo *HardwareInfo) {
+	system, _ := d.output(ctx, "uname -s")
+	osInfo.System = strings.ToLower(system)
+	hardware, _ := d.output(ctx, "uname -m")
+	hwInfo.Hardware = strings.ToLower(hardware)
+	osInfo.Kernel, _ = d.output(ctx, kernelCmd)
+	switch osInfo.System {
+	case "darwin":
+		if output, ok := d.output(ctx, swVersCmd); ok {
+			parseSwVers(output, osInfo)
+		}
+		hwInfo.Version, _ = d.output(ctx, appleVersionCmd)
+		return
+	case "linux":
+		hwInfo.Version, _ = d.output(ctx, dmiVersionCmd)
+	}
+	if output, ok := d.output(ctx, osReleaseCmd); ok {
+		parseOSRelease(output, osInfo)
+		return
+	}
+	if output, ok := d.output(ctx, anyReleaseCmd); ok {
+		parseAnyRelease(output, osInfo)
+		if osInfo.Release != "" {
+			return
+		}
+	}
+	if output, ok := d.output(ctx, lsbReleaseCmd); ok {
+		parseLSBRelease(output, osInfo)
+	}
+}
+
+// parseKeyValues parses KEY=value lines, values may be single or double quoted
+func parseKeyValues(output string) map[string]string {
+	ret := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		index := strings.Index(line, "=")
+		if index == -1 {
+			continue
+		}
+		key := strings.TrimSpace(line[:index])
+		value := strings.TrimSpace(line[index+1:])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		ret[key] = strings.ReplaceAll(value, `\"`, `"`)
+	}
+	return ret
+}
+
+// parseOSRelease parses freedesktop os-release file
+func parseOSRelease(output string, osInfo *OSInfo) {
+	values := parseKeyValues(output)
+	osInfo.DistributorID = strings.ToLower(values["ID"])
+	osInfo.Name = strings.ToLower(values["NAME"])
+	osInfo.Description = values["PRETTY_NAME"]
+	osInfo.Release = strings.ToLower(values["VERSION_ID"])
+	if like := values["ID_LIKE"]; like != "" {
+		osInfo.IDLike = strings.Fields(strings.ToLower(like))
+	}
+	codename := values["VERSION_CODENAME"]
+	if codename == "" {
+		codename = values["UBUNTU_CODENAME"]
+	}
+	if codename == "" {
+		codename = versionCodename(osInfo.DistributorID, values["VERSION"])
+	}
+	osInfo.Codename = strings.ToLower(codename)
+	if osInfo.Description == "" {
+		osInfo.Description = strings.TrimSpace(values["NAME"] + " " + values["VERSION"])
+	}
+}
+
+// versionCodename extracts codename from VERSION, e.g. 9 (stretch) or 20.04.6 LTS (Focal Fossa) and 14.04.6 LTS, Trusty Tahr on ubuntu
+func versionCodename(id, version string) string {
+	var codename string
+	if start, end := strings.Index(version, "("), strings.LastIndex(version, ")"); start != -1 && end > start {
+		codename = version[start+1 : end]
+	} else if index := strings.Index(version, ", "); index != -1 {
+		codename = version[index+2:]
+	}
+	fields := strings.Fields(codename)
+	switch {
+	case len(fields) == 1:
+		return fields[0]
+	case len(fields) > 1 && id == "ubuntu":
+		// Ubuntu uses adjective animal pairs, the adjective is the codename
+		return fields[0]
+	}
+	return ""
+}
+
+// parseAnyRelease parses /etc/*-release output, either lsb-release key values or a free form release line
+func parseAnyRelease(output string, osInfo *OSInfo) {
+	values := parseKeyValues(output)
+	if values["DISTRIB_ID"] != "" {
+		osInfo.DistributorID = strings.ToLower(values["DISTRIB_ID"])
+		osInfo.Name = osInfo.DistributorID
+		osInfo.Release = strings.ToLower(values["DISTRIB_RELEASE"])
+		osInfo.Codename = strings.ToLower(values["DISTRIB_CODENAME"])
+		osInfo.Description = values["DISTRIB_DESCRIPTION"]
+		return
+	}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		matched := releaseExpr.FindStringSubmatch(line)
+		if len(matched) == 0 {
+			continue
+		}
+		osInfo.Description = line
+		osInfo.Name = strings.ToLower(matched[1])
+		osInfo.DistributorID = strings.ToLower(strings.Fields(matched[1])[0])
+		osInfo.Release = matched[2]
+		osInfo.Codename = strings.ToLower(matched[3])
+		return
+	}
+}
+
+// parseLSBRelease parses lsb_release -a output
+func parseLSBRelease(output string, osInfo *OSInfo) {
+	for _, line := range strings.Split(output, "\n") {
+		index := strings.Index(line, ":")
+		if index == -1 {
+			continue
+		}
+		key := strings.ToLower(strings.ReplaceAll(line[:index], " ", ""))
+		value := strings.TrimSpace(line[index+1:])
+		switch key {
+		case "distributorid":
+			osInfo.DistributorID = strings.ToLower(value)
+			osInfo.Name = osInfo.DistributorID
+		case "description":
+			osInfo.Description = value
+		case "release":
+			osInfo.Release = strings.ToLower(value)
+		case "codename":
+			osInfo.Codename = strings.ToLower(value)
+		}
+	}
+}
+
+// parseSwVers parses macOS sw_vers output
+func parseSwVers(output string, osInfo *OSInfo) {
+	var build string
+	for _, line := range strings.Split(output, "\n") {
+		index := strings.Index(line, ":")
+		if index == -1 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:index]))
+		value := strings.TrimSpace(line[index+1:])
+		switch key {
+		case "productname":
+			osInfo.Name = strings.ToLower(value)
+			osInfo.Description = value
+		case "productversion":
+			osInfo.Release = strings.ToLower(value)
+		case "buildversion":
+			build = value
+		}
+	}
+	osInfo.DistributorID = "apple"
+	osInfo.Codename = macOSCodenames[majorVersion(osInfo.Release)]
+	if osInfo.Description != "" {
+		osInfo.Description = strings.TrimSpace(osInfo.Description + " " + osInfo.Release)
+		if build != "" {
+			osInfo.Description += " (" + build + ")"
+		}
+	}
+}
+
+// majorVersion returns macOS major version, 10.x releases are identified by major and minor
+func majorVersion(release string) string {
+	parts := strings.Split(release, ".")
+	if len(parts) > 1 && parts[0] == "10" {
+		return parts[0] + "." + parts[1]
+	}
+	return parts[0]
+}
@@ -0,0 +1,151 @@
+package gosh_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/gosh"
+	"github.com/viant/gosh/runner"
+	"github.com/viant/gosh/runner/replay"
+)
+
+func TestService_DetectSystem(t *testing.T) {
+	var testCases = []struct {
+		description string
+		outputs     map[string]string
+		expectOS    *gosh.OSInfo
+		expectHW    *gosh.HardwareInfo
+	}{
+		{
+			description: "ubuntu os-release",
+			outputs: map[string]string{
+				"uname -s":                              "Linux",
+				"uname -m":                              "x86_64",
+				"uname -r":                              "5.15.0-91-generic",
+				"cat /sys/class/dmi/id/product_version": "1.0",
+				"cat /etc/os-release 2>/dev/null || cat /usr/lib/os-release": `PRETTY_NAME="Ubuntu 22.04.3 LTS"
+NAME="Ubuntu"
+VERSION_ID="22.04"
+VERSION="22.04.3 LTS (Jammy Jellyfish)"
+VERSION_CODENAME=jammy
+ID=ubuntu
+ID_LIKE=debian
+HOME_URL="https://www.ubuntu.com/"
+UBUNTU_CODENAME=jammy`,
+			},
+			expectOS: &gosh.OSInfo{System: "linux", Name: "ubuntu", DistributorID: "ubuntu", IDLike: []string{"debian"}, Description: "Ubuntu 22.04.3 LTS", Release: "22.04", Codename: "jammy", Kernel: "5.15.0-91-generic"},
+			expectHW: &gosh.HardwareInfo{Hardware: "x86_64", Architecture: "amd64", Arch: "x64", Version: "1.0"},
+		},
+		{
+			description: "alpine os-release",
+			outputs: map[string]string{
+				"uname -s": "Linux",
+				"uname -m": "aarch64",
+				"uname -r": "6.6.12-linuxkit",
+				"cat /etc/os-release 2>/dev/null || cat /usr/lib/os-release": `NAME="Alpine Linux"
+ID=alpine
+VERSION_ID=3.19.1
+PRETTY_NAME="Alpine Linux v3.19"
+HOME_URL="https://alpinelinux.org/"`,
+			},
+			expectOS: &gosh.OSInfo{System: "linux", Name: "alpine linux", DistributorID: "alpine", Description: "Alpine Linux v3.19", Release: "3.19.1", Kernel: "6.6.12-linuxkit"},
+			expectHW: &gosh.HardwareInfo{Hardware: "aarch64", Architecture: "arm64", Arch: "aarch64"},
+		},
+		{
+			description: "amazon linux os-release",
+			outputs: map[string]string{
+				"uname -s": "Linux",
+				"uname -m": "x86_64",
+				"uname -r": "4.14.336-257.562.amzn2.x86_64",
+				"cat /etc/os-release 2>/dev/null || cat /usr/lib/os-release": `NAME="Amazon Linux"
+VERSION="2"
+ID="amzn"
+ID_LIKE="centos rhel fedora"
+VERSION_ID="2"
+PRETTY_NAME="Amazon Linux 2"`,
+			},
+			expectOS: &gosh.OSInfo{System: "linux", Name: "amazon linux", DistributorID: "amzn", IDLike: []string{"centos", "rhel", "fedora"}, Description: "Amazon Linux 2", Release: "2", Kernel: "4.14.336-257.562.amzn2.x86_64"},
+			expectHW: &gosh.HardwareInfo{Hardware: "x86_64", Architecture: "amd64", Arch: "x64"},
+		},
+		{
+			description: "debian codename from version",
+			outputs: map[string]string{
+				"uname -s": "Linux",
+				"uname -m": "x86_64",
+				"cat /etc/os-release 2>/dev/null || cat /usr/lib/os-release": `PRETTY_NAME="Debian GNU/Linux 9 (stretch)"
+NAME="Debian GNU/Linux"
+VERSION_ID="9"
+VERSION="9 (stretch)"
+ID=debian`,
+			},
+			expectOS: &gosh.OSInfo{System: "linux", Name: "debian gnu/linux", DistributorID: "debian", Description: "Debian GNU/Linux 9 (stretch)", Release: "9", Codename: "stretch"},
+			expectHW: &gosh.HardwareInfo{Hardware: "x86_64", Architecture: "amd64", Arch: "x64"},
+		},
+		{
+			description: "centos 6 redhat-release",
+			outputs: map[string]string{
+				"uname -s":           "Linux",
+				"uname -m":           "x86_64",
+				"uname -r":           "2.6.32-754.el6.x86_64",
+				"cat /etc/*-release": "CentOS release 6.10 (Final)\nCentOS release 6.10 (Final)",
+			},
+			expectOS: &gosh.OSInfo{System: "linux", Name: "centos", DistributorID: "centos", Description: "CentOS release 6.10 (Final)", Release: "6.10", Codename: "final", Kernel: "2.6.32-754.el6.x86_64"},
+			expectHW: &gosh.HardwareInfo{Hardware: "x86_64", Architecture: "amd64", Arch: "x64"},
+		},
+		{
+			description: "lsb-release file",
+			outputs: map[string]string{
+				"uname -s": "Linux",
+				"uname -m": "x86_64",
+				"cat /etc/*-release": `DISTRIB_ID=Ubuntu
+DISTRIB_RELEASE=12.04
+DISTRIB_CODENAME=precise
+DISTRIB_DESCRIPTION="Ubuntu 12.04.5 LTS"`,
+			},
+			expectOS: &gosh.OSInfo{System: "linux", Name: "ubuntu", DistributorID: "ubuntu", Description: "Ubuntu 12.04.5 LTS", Release: "12.04", Codename: "precise"},
+			expectHW: &gosh.HardwareInfo{Hardware: "x86_64", Architecture: "amd64", Arch: "x64"},
+		},
+		{
+			description: "lsb_release fallback",
+			outputs: map[string]string{
+				"uname -s": "Linux",
+				"uname -m": "x86_64",
+				"lsb_release -a": `No LSB modules are available.
+Distributor ID:	Ubuntu
+Description:	Ubuntu 20.04.6 LTS
+Release:	20.04
+Codename:	focal`,
+			},
+			expectOS: &gosh.OSInfo{System: "linux", Name: "ubuntu", DistributorID: "ubuntu", Description: "Ubuntu 20.04.6 LTS", Release: "20.04", Codename: "focal"},
+			expectHW: &gosh.HardwareInfo{Hardware: "x86_64", Architecture: "amd64", Arch: "x64"},
+		},
+		{
+			description: "macOS sw_vers",
+			outputs: map[string]string{
+				"uname -s":           "Darwin",
+				"uname -m":           "arm64",
+				"uname -r":           "23.2.0",
+				"sysctl -n hw.model": "MacBookPro18,3",
+				"sw_vers": `ProductName:		macOS
+ProductVersion:		14.2.1
+BuildVersion:		23C71`,
+			},
+			expectOS: &gosh.OSInfo{System: "darwin", Name: "macos", DistributorID: "apple", Description: "macOS 14.2.1 (23C71)", Release: "14.2.1", Codename: "sonoma", Kernel: "23.2.0"},
+			expectHW: &gosh.HardwareInfo{Hardware: "arm64", Architecture: "arm64", Arch: "x64", Version: "MacBookPro18,3"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		var commands []*runner.Command
+		for command, output := range testCase.outputs {
+			commands = append(commands, runner.NewCommand(command, output, nil))
+		}
+		srv, err := gosh.New(context.Background(), replay.New(1, commands))
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		assert.EqualValues(t, testCase.expectOS, srv.OsInfo(), testCase.description)
+		assert.EqualValues(t, testCase.expectHW, srv.HardwareInfo(), testCase.description)
+	}
+}
@@ -0,0 +1,43 @@
+package expect
+
+import (
+	"context"
+	"errors"
+
+	"github.com/viant/gosh/runner"
+)
+
+type (
+	// Step represents a dialog step, Response is sent as a line whenever Pattern matches
+	Step struct {
+		Pattern  *Pattern
+		Response string
+		// Secret masks the response in history
+		Secret bool
+	}
+
+	// Dialog represents a table of prompts and responses
+	Dialog []*Step
+)
+
+// Interact answers prompts with dialog responses until the command completes, a prompt may be answered many times.
+// It returns TimeoutError if no prompt shows up within the step timeouts while the command is running
+func (s *Session) Interact(ctx context.Context, dialog Dialog) (*runner.Result, error) {
+	patterns := make([]*Pattern, len(dialog))
+	for i, step := range dialog {
+		patterns[i] = step.Pattern
+	}
+	for {
+		match, err := s.Expect(ctx, patterns...)
+		if errors.Is(err, ErrEOF) {
+			return s.Wait(ctx)
+		}
+		if err != nil {
+			return nil, err
+		}
+		step := dialog[match.Index]
+		if err = s.send(ctx, step.Response+"\n", step.Secret); err != nil {
+			return nil, err
+		}
+	}
+}
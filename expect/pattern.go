@@ -0,0 +1,45 @@
+package expect
+
+import (
+	"regexp"
+	"time"
+)
+
+type (
+	// Pattern represents expected output
+	Pattern struct {
+		Expr *regexp.Regexp
+		// Timeout limits how long the pattern is awaited, session default is used when zero
+		Timeout time.Duration
+	}
+
+	// Match represents matched output
+	Match struct {
+		Pattern *Pattern
+		// Index is matched pattern position in Expect arguments
+		Index int
+		// Text is matched text
+		Text string
+		// Groups are regular expression submatches
+		Groups []string
+		// Before is output consumed before the match
+		Before string
+	}
+)
+
+// WithTimeout returns the pattern with supplied timeout
+func (p *Pattern) WithTimeout(timeout time.Duration) *Pattern {
+	ret := *p
+	ret.Timeout = timeout
+	return &ret
+}
+
+// Regexp creates a pattern matching regular expression, it panics if expr does not compile
+func Regexp(expr string) *Pattern {
+	return &Pattern{Expr: regexp.MustCompile(expr)}
+}
+
+// String creates a pattern matching literal text
+func String(text string) *Pattern {
+	return &Pattern{Expr: regexp.MustCompile(regexp.QuoteMeta(text))}
+}
@@ -0,0 +1,267 @@
+package expect
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/viant/gosh/runner"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+	secretMask     = "******"
+)
+
+// ErrEOF is returned when the command exited before any pattern matched
+var ErrEOF = errors.New("expect: command exited")
+
+type (
+	// Session represents an interactive command, its stdout and stderr are matched together
+	Session struct {
+		runner     runner.Runner
+		command    string
+		timeout    time.Duration
+		history    *runner.History
+		sends      bool
+		startedAt  time.Time
+		runOptions []runner.Option
+		mux        sync.Mutex
+		buffer     string // output not consumed yet
+		transcript string // output consumed since the last send
+		streamed   bool
+		changed    chan struct{}
+		done       chan struct{}
+		result     *runner.Result
+		err        error
+	}
+
+	// Option represents session option
+	Option func(s *Session)
+)
+
+// WithTimeout sets default pattern timeout
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *Session) {
+		s.timeout = timeout
+	}
+}
+
+// WithHistory records the command once completed
+func WithHistory(history *runner.History) Option {
+	return func(s *Session) {
+		s.history = history
+	}
+}
+
+// WithSendHistory records every sent line with the output that prompted it in history set with WithHistory,
+// lines sent with SendSecret are masked
+func WithSendHistory() Option {
+	return func(s *Session) {
+		s.sends = true
+	}
+}
+
+// WithRunOptions sets options the command is run with
+func WithRunOptions(options ...runner.Option) Option {
+	return func(s *Session) {
+		s.runOptions = append(s.runOptions, options...)
+	}
+}
+
+// Spawn runs supplied command with the runner in the background, the command reads data sent with Send.
+// Runners that do not report output as it is read make it available once the command completes
+func Spawn(ctx context.Context, aRunner runner.Runner, command string, options ...Option) *Session {
+	ret := &Session{
+		runner:  aRunner,
+		command: command,
+		timeout: defaultTimeout,
+		changed: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	for _, option := range options {
+		option(ret)
+	}
+	ret.startedAt = time.Now()
+	go ret.run(ctx)
+	return ret
+}
+
+func (s *Session) run(ctx context.Context) {
+	options := append(s.runOptions, runner.WithInteractive(), runner.WithChunkListener(func(stream runner.Stream, chunk string) {
+		s.write(chunk, true)
+	}))
+	result, err := runner.RunResult(ctx, s.runner, s.command, options...)
+	s.mux.Lock()
+	streamed := s.streamed
+	s.mux.Unlock()
+	if !streamed && result != nil {
+		s.write(result.Stdout+result.Stderr, false)
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.result, s.err = result, err
+	if s.history != nil {
+		completed := runner.NewResultCommand(s.command, result, err)
+		completed.StartedAt, completed.FinishedAt = s.startedAt, time.Now()
+		completed.Duration = completed.FinishedAt.Sub(s.startedAt)
+		_ = s.history.Append(completed)
+	}
+	close(s.done)
+}
+
+func (s *Session) write(output string, streamed bool) {
+	if output == "" {
+		return
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.streamed = s.streamed || streamed
+	s.buffer += output
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Expect waits until output matches any of the patterns and consumes it up to the end of the match.
+// A pattern is awaited for its timeout; TimeoutError is returned once every pattern has expired, ErrEOF if the command exited
+func (s *Session) Expect(ctx context.Context, patterns ...*Pattern) (*Match, error) {
+	startedAt := time.Now()
+	longest := time.Duration(0)
+	for _, pattern := range patterns {
+		if timeout := s.patternTimeout(pattern); timeout > longest {
+			longest = timeout
+		}
+	}
+	for {
+		elapsed := time.Since(startedAt)
+		s.mux.Lock()
+		if match := s.match(patterns, elapsed); match != nil {
+			s.mux.Unlock()
+			return match, nil
+		}
+		changed, buffer := s.changed, s.buffer
+		s.mux.Unlock()
+		select {
+		case <-s.done:
+			select {
+			case <-changed: // output written before completion, match again
+				continue
+			default:
+			}
+			return nil, ErrEOF
+		default:
+		}
+		wait := longest - elapsed
+		for _, pattern := range patterns {
+			if remaining := s.patternTimeout(pattern) - elapsed; remaining > 0 && remaining < wait {
+				wait = remaining
+			}
+		}
+		if wait <= 0 {
+			return nil, &runner.TimeoutError{Command: s.command, Host: runner.HostOf(s.runner), Timeout: longest, Stream: runner.Stdout, Stdout: buffer}
+		}
+		select {
+		case <-changed:
+		case <-s.done:
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// match returns the earliest match of a pattern that has not expired, caller must hold mux
+func (s *Session) match(patterns []*Pattern, elapsed time.Duration) *Match {
+	var ret *Match
+	var position []int
+	for i, pattern := range patterns {
+		if elapsed > s.patternTimeout(pattern) {
+			continue
+		}
+		candidate := pattern.Expr.FindStringSubmatchIndex(s.buffer)
+		if candidate == nil || (position != nil && candidate[0] >= position[0]) {
+			continue
+		}
+		position = candidate
+		ret = &Match{Pattern: pattern, Index: i}
+	}
+	if ret == nil {
+		return nil
+	}
+	ret.Before = s.buffer[:position[0]]
+	ret.Text = s.buffer[position[0]:position[1]]
+	for i := 2; i < len(position); i += 2 {
+		group := ""
+		if position[i] >= 0 {
+			group = s.buffer[position[i]:position[i+1]]
+		}
+		ret.Groups = append(ret.Groups, group)
+	}
+	s.transcript += s.buffer[:position[1]]
+	s.buffer = s.buffer[position[1]:]
+	return ret
+}
+
+func (s *Session) patternTimeout(pattern *Pattern) time.Duration {
+	if pattern.Timeout > 0 {
+		return pattern.Timeout
+	}
+	return s.timeout
+}
+
+// Send sends data to the command
+func (s *Session) Send(ctx context.Context, data string) error {
+	return s.send(ctx, data, false)
+}
+
+// SendLine sends line to the command
+func (s *Session) SendLine(ctx context.Context, line string) error {
+	return s.send(ctx, line+"\n", false)
+}
+
+// SendSecret sends line to the command, it is masked in history, see WithSendHistory
+func (s *Session) SendSecret(ctx context.Context, line string) error {
+	return s.send(ctx, line+"\n", true)
+}
+
+func (s *Session) send(ctx context.Context, data string, secret bool) error {
+	startedAt := time.Now()
+	_, err := s.runner.Send(ctx, []byte(data))
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.history != nil && s.sends {
+		entry := &runner.Command{Stdin: strings.TrimSuffix(data, "\n"), StartedAt: startedAt, FinishedAt: time.Now()}
+		entry.Duration = entry.FinishedAt.Sub(startedAt)
+		if secret {
+			entry.Stdin = secretMask
+		}
+		if s.transcript != "" {
+			entry.Stdout = strings.Split(s.transcript, "\n")
+		}
+		if err != nil {
+			entry.Error = []string{err.Error()}
+		}
+		_ = s.history.Append(entry)
+	}
+	s.transcript = ""
+	return err
+}
+
+// Done returns a channel closed when the command has completed
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Wait waits for the command to complete and returns its result
+func (s *Session) Wait(ctx context.Context) (*runner.Result, error) {
+	select {
+	case <-s.done:
+		s.mux.Lock()
+		defer s.mux.Unlock()
+		return s.result, s.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,81 @@
+package expect
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/gosh/runner"
+	"github.com/viant/gosh/runner/local"
+)
+
+func TestSession_Expect(t *testing.T) {
+	ctx := context.Background()
+	aRunner := local.New()
+	defer aRunner.Close()
+	history := &runner.History{}
+	session := Spawn(ctx, aRunner, `printf 'Name: '; read name; echo "hello $name"; printf 'Continue (yes/no)? '; read answer; echo "answer $answer"`, WithHistory(history))
+	match, err := session.Expect(ctx, String("Name: "))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 0, match.Index)
+	assert.Nil(t, session.SendLine(ctx, "gosh"))
+	match, err = session.Expect(ctx, Regexp(`hello (\w+)`), String("(yes/no)"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 0, match.Index)
+	assert.Equal(t, []string{"gosh"}, match.Groups)
+
+	_, err = session.Expect(ctx, String("never").WithTimeout(200*time.Millisecond))
+	timeoutErr := &runner.TimeoutError{}
+	if assert.ErrorAs(t, err, &timeoutErr) {
+		assert.Contains(t, timeoutErr.Stdout, "(yes/no)")
+		assert.Equal(t, runner.LocalHost, timeoutErr.Host)
+	}
+	match, err = session.Expect(ctx, String("(yes/no)? "))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "\nContinue ", match.Before)
+	assert.Nil(t, session.SendLine(ctx, "yes"))
+	_, err = session.Expect(ctx, String("never"))
+	assert.True(t, errors.Is(err, ErrEOF))
+	result, err := session.Wait(ctx)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 0, result.ExitCode)
+		assert.Contains(t, result.Stdout, "answer yes")
+	}
+	if commands := history.Entries(); assert.Len(t, commands, 1) { // sent lines are recorded with WithSendHistory only
+		assert.Equal(t, 0, commands[0].ExitCode)
+	}
+}
+
+func TestSession_Interact(t *testing.T) {
+	ctx := context.Background()
+	aRunner := local.New()
+	defer aRunner.Close()
+	history := &runner.History{}
+	session := Spawn(ctx, aRunner, `for i in 1 2; do printf 'Password: '; read secret; done; printf 'Proceed (yes/no)? '; read answer; echo "$secret $answer"`,
+		WithHistory(history), WithSendHistory(), WithTimeout(3*time.Second))
+	result, err := session.Interact(ctx, Dialog{
+		{Pattern: String("Password:"), Response: "s3cret", Secret: true},
+		{Pattern: String("(yes/no)"), Response: "yes"},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Contains(t, result.Stdout, "s3cret yes")
+	if commands := history.Entries(); assert.Len(t, commands, 4) {
+		assert.Equal(t, secretMask, commands[0].Stdin)
+		assert.Equal(t, []string{"Password:"}, commands[0].Stdout)
+		assert.Equal(t, secretMask, commands[1].Stdin)
+		assert.Equal(t, "yes", commands[2].Stdin)
+		assert.Equal(t, 0, commands[3].ExitCode)
+		assert.Equal(t, 3, commands[3].Index)
+	}
+}
@@ -0,0 +1,324 @@
+package facts
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// CollectorCPU collects processor count and model
+	CollectorCPU = "cpu"
+	// CollectorMemory collects total and available memory
+	CollectorMemory = "memory"
+	// CollectorDisks collects mounted filesystems
+	CollectorDisks = "disks"
+	// CollectorHostname collects hostname and FQDN
+	CollectorHostname = "hostname"
+	// CollectorTimezone collects timezone
+	CollectorTimezone = "timezone"
+	// CollectorShells collects installed login shells
+	CollectorShells = "shells"
+)
+
+const (
+	linux  = "linux"
+	darwin = "darwin"
+)
+
+var posix = []string{DialectPosix}
+
+func builtins() []*Collector {
+	return []*Collector{
+		{Name: CollectorCPU, Systems: []string{linux}, Dialects: posix, Collect: linuxCPU},
+		{Name: CollectorCPU, Systems: []string{darwin}, Dialects: posix, Collect: darwinCPU},
+		{Name: CollectorMemory, Systems: []string{linux}, Dialects: posix, Collect: linuxMemory},
+		{Name: CollectorMemory, Systems: []string{darwin}, Dialects: posix, Collect: darwinMemory},
+		{Name: CollectorDisks, Systems: []string{linux}, Dialects: posix, Collect: linuxDisks},
+		{Name: CollectorDisks, Systems: []string{darwin}, Dialects: posix, Collect: darwinDisks},
+		{Name: CollectorHostname, Dialects: posix, Collect: hostname},
+		{Name: CollectorTimezone, Systems: []string{linux, darwin}, Dialects: posix, Collect: timezone},
+		{Name: CollectorShells, Systems: []string{linux, darwin}, Dialects: posix, Collect: shells},
+	}
+}
+
+func linuxCPU(ctx context.Context, session *Session, facts *Facts) error {
+	output, err := session.Output(ctx, "cat /proc/cpuinfo")
+	if err != nil {
+		return err
+	}
+	facts.CPU = parseCPUInfo(output)
+	if facts.CPU.Count == 0 {
+		if count, err := session.Output(ctx, "nproc"); err == nil {
+			facts.CPU.Count, _ = strconv.Atoi(count)
+		}
+	}
+	return nil
+}
+
+// parseCPUInfo parses /proc/cpuinfo
+func parseCPUInfo(output string) *CPU {
+	ret := &CPU{}
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		switch key {
+		case "processor":
+			ret.Count++
+		case "model name", "Model", "cpu model", "Hardware":
+			if ret.Model == "" {
+				ret.Model = value
+			}
+		}
+	}
+	return ret
+}
+
+func darwinCPU(ctx context.Context, session *Session, facts *Facts) error {
+	count, err := session.Output(ctx, "sysctl -n hw.ncpu")
+	if err != nil {
+		return err
+	}
+	facts.CPU = &CPU{}
+	if facts.CPU.Count, err = strconv.Atoi(count); err != nil {
+		return fmt.Errorf("invalid hw.ncpu: %v", count)
+	}
+	facts.CPU.Model, _ = session.Output(ctx, "sysctl -n machdep.cpu.brand_string")
+	return nil
+}
+
+func linuxMemory(ctx context.Context, session *Session, facts *Facts) error {
+	output, err := session.Output(ctx, "cat /proc/meminfo")
+	if err != nil {
+		return err
+	}
+	facts.Memory = parseMemInfo(output)
+	return nil
+}
+
+// parseMemInfo parses /proc/meminfo, values are reported in kB
+func parseMemInfo(output string) *Memory {
+	ret := &Memory{}
+	values := map[string]int64{}
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		if len(fields) > 1 && strings.EqualFold(fields[1], "kB") {
+			size *= 1024
+		}
+		values[strings.TrimSpace(key)] = size
+	}
+	ret.TotalBytes = values["MemTotal"]
+	if available, ok := values["MemAvailable"]; ok {
+		ret.AvailableBytes = available
+	} else {
+		ret.AvailableBytes = values["MemFree"] + values["Buffers"] + values["Cached"]
+	}
+	return ret
+}
+
+func darwinMemory(ctx context.Context, session *Session, facts *Facts) error {
+	total, err := session.Output(ctx, "sysctl -n hw.memsize")
+	if err != nil {
+		return err
+	}
+	facts.Memory = &Memory{}
+	if facts.Memory.TotalBytes, err = strconv.ParseInt(total, 10, 64); err != nil {
+		return fmt.Errorf("invalid hw.memsize: %v", total)
+	}
+	if output, err := session.Output(ctx, "vm_stat"); err == nil {
+		facts.Memory.AvailableBytes = parseVMStat(output)
+	}
+	return nil
+}
+
+// parseVMStat returns available bytes (free, inactive and speculative pages) from vm_stat output
+func parseVMStat(output string) int64 {
+	pageSize := int64(4096)
+	var pages int64
+	for _, line := range strings.Split(output, "\n") {
+		if strings.Contains(line, "page size of") {
+			fields := strings.Fields(line[strings.Index(line, "page size of")+len("page size of"):])
+			if len(fields) > 0 {
+				if size, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+					pageSize = size
+				}
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Pages free", "Pages inactive", "Pages speculative":
+			count, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(value), "."), 10, 64)
+			if err == nil {
+				pages += count
+			}
+		}
+	}
+	return pages * pageSize
+}
+
+func linuxDisks(ctx context.Context, session *Session, facts *Facts) error {
+	output, err := session.Output(ctx, "df -P -k")
+	if err != nil {
+		return err
+	}
+	facts.Disks = parseDF(output)
+	if mounts, err := session.Output(ctx, "cat /proc/mounts"); err == nil {
+		setFSTypes(facts.Disks, parseProcMounts(mounts))
+	}
+	return nil
+}
+
+func darwinDisks(ctx context.Context, session *Session, facts *Facts) error {
+	output, err := session.Output(ctx, "df -P -k")
+	if err != nil {
+		return err
+	}
+	facts.Disks = parseDF(output)
+	if mounts, err := session.Output(ctx, "mount"); err == nil {
+		setFSTypes(facts.Disks, parseMount(mounts))
+	}
+	return nil
+}
+
+// parseDF parses POSIX df -P -k output
+func parseDF(output string) []*Disk {
+	var ret []*Disk
+	for i, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if i == 0 || len(fields) < 6 {
+			continue
+		}
+		size, err1 := strconv.ParseInt(fields[1], 10, 64)
+		used, err2 := strconv.ParseInt(fields[2], 10, 64)
+		available, err3 := strconv.ParseInt(fields[3], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		ret = append(ret, &Disk{
+			Device:         fields[0],
+			MountPoint:     strings.Join(fields[5:], " "),
+			SizeBytes:      size * 1024,
+			UsedBytes:      used * 1024,
+			AvailableBytes: available * 1024,
+		})
+	}
+	return ret
+}
+
+// parseProcMounts returns filesystem types keyed by mount point from /proc/mounts
+func parseProcMounts(output string) map[string]string {
+	ret := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		ret[strings.ReplaceAll(fields[1], `\040`, " ")] = fields[2]
+	}
+	return ret
+}
+
+// parseMount returns filesystem types keyed by mount point from BSD mount output, e.g. /dev/disk3s1s1 on / (apfs, sealed, local)
+func parseMount(output string) map[string]string {
+	ret := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		on := strings.Index(line, " on ")
+		options := strings.LastIndex(line, " (")
+		if on == -1 || options < on {
+			continue
+		}
+		fsType, _, _ := strings.Cut(strings.TrimSuffix(line[options+2:], ")"), ",")
+		ret[line[on+4:options]] = strings.TrimSpace(fsType)
+	}
+	return ret
+}
+
+func setFSTypes(disks []*Disk, types map[string]string) {
+	for _, disk := range disks {
+		disk.FSType = types[disk.MountPoint]
+	}
+}
+
+func hostname(ctx context.Context, session *Session, facts *Facts) error {
+	name, err := session.Output(ctx, "hostname")
+	if err != nil {
+		return err
+	}
+	facts.Hostname = name
+	facts.FQDN = name
+	if fqdn, err := session.Output(ctx, "hostname -f 2>/dev/null"); err == nil && fqdn != "" {
+		facts.FQDN = fqdn
+	}
+	return nil
+}
+
+func timezone(ctx context.Context, session *Session, facts *Facts) error {
+	output, err := session.Output(ctx, "date +'%Z %z'")
+	if err != nil {
+		return err
+	}
+	facts.Timezone = &Timezone{}
+	if fields := strings.Fields(output); len(fields) == 2 {
+		facts.Timezone.Abbreviation, facts.Timezone.Offset = fields[0], fields[1]
+	}
+	if session.Target.System == linux {
+		if name, err := session.Output(ctx, "cat /etc/timezone 2>/dev/null"); err == nil && name != "" {
+			facts.Timezone.Name = name
+			return nil
+		}
+	}
+	if link, err := session.Output(ctx, "readlink /etc/localtime"); err == nil {
+		facts.Timezone.Name = zoneName(link)
+	}
+	return nil
+}
+
+// zoneName returns IANA zone name from zoneinfo path, e.g. /var/db/timezone/zoneinfo/Europe/Warsaw
+func zoneName(path string) string {
+	if index := strings.LastIndex(path, "zoneinfo/"); index != -1 {
+		return path[index+len("zoneinfo/"):]
+	}
+	return ""
+}
+
+func shells(ctx context.Context, session *Session, facts *Facts) error {
+	output, err := session.Output(ctx, "cat /etc/shells")
+	if err != nil {
+		return err
+	}
+	facts.Shells = parseShells(output)
+	return nil
+}
+
+// parseShells parses /etc/shells
+func parseShells(output string) []string {
+	var ret []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ret = append(ret, line)
+	}
+	return ret
+}
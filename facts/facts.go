@@ -0,0 +1,66 @@
+package facts
+
+import "time"
+
+type (
+	// Facts represents collected host facts
+	Facts struct {
+		CPU         *CPU
+		Memory      *Memory
+		Disks       []*Disk
+		Hostname    string
+		FQDN        string
+		Timezone    *Timezone
+		Shells      []string
+		Values      map[string]interface{} `json:",omitempty"` // values set by custom collectors
+		Errors      map[string]string      `json:",omitempty"` // collector errors keyed by collector name
+		CollectedAt time.Time
+	}
+
+	// CPU represents processor details
+	CPU struct {
+		Count int
+		Model string
+	}
+
+	// Memory represents memory details
+	Memory struct {
+		TotalBytes     int64
+		AvailableBytes int64
+	}
+
+	// Disk represents a mounted filesystem
+	Disk struct {
+		Device         string
+		MountPoint     string
+		FSType         string
+		SizeBytes      int64
+		UsedBytes      int64
+		AvailableBytes int64
+	}
+
+	// Timezone represents host timezone
+	Timezone struct {
+		Name         string // IANA name, e.g. Europe/Warsaw
+		Abbreviation string // e.g. CET
+		Offset       string // e.g. +0100
+	}
+)
+
+// Set sets a custom collector value
+func (f *Facts) Set(key string, value interface{}) {
+	if f.Values == nil {
+		f.Values = map[string]interface{}{}
+	}
+	f.Values[key] = value
+}
+
+// Disk returns disk mounted at supplied mount point
+func (f *Facts) Disk(mountPoint string) *Disk {
+	for _, disk := range f.Disks {
+		if disk.MountPoint == mountPoint {
+			return disk
+		}
+	}
+	return nil
+}
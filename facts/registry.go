@@ -0,0 +1,132 @@
+package facts
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/viant/gosh/runner"
+)
+
+const (
+	// DialectPosix represents POSIX shells (sh, bash, zsh, dash, ash)
+	DialectPosix = "posix"
+	// DialectPowerShell represents PowerShell
+	DialectPowerShell = "powershell"
+	// DialectCmd represents cmd.exe
+	DialectCmd = "cmd"
+)
+
+type (
+	// Target represents host the facts are collected from
+	Target struct {
+		System  string // OS family, lower case uname -s, e.g. linux, darwin
+		Dialect string // shell dialect
+	}
+
+	// Collector represents a fact collector
+	Collector struct {
+		Name     string
+		Systems  []string // supported OS families, any when empty
+		Dialects []string // supported shell dialects, any when empty
+		Collect  func(ctx context.Context, session *Session, facts *Facts) error
+	}
+
+	// Session represents collector access to the host
+	Session struct {
+		Runner runner.Runner
+		Target *Target
+	}
+
+	// Registry represents collector registry
+	Registry struct {
+		mux        sync.RWMutex
+		collectors []*Collector
+	}
+)
+
+// Supports returns true if collector supports target
+func (c *Collector) Supports(target *Target) bool {
+	return matches(c.Systems, target.System) && matches(c.Dialects, target.Dialect)
+}
+
+func matches(supported []string, candidate string) bool {
+	if len(supported) == 0 {
+		return true
+	}
+	for _, item := range supported {
+		if strings.EqualFold(item, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// Output runs command and returns its trimmed stdout, non zero exit code is reported as error
+func (s *Session) Output(ctx context.Context, command string) (string, error) {
+	result, err := runner.RunResult(ctx, s.Runner, command)
+	if err != nil {
+		return "", err
+	}
+	if result.ExitCode != 0 {
+		return "", fmt.Errorf("%v: exit code %v: %v", command, result.ExitCode, strings.TrimSpace(result.Stderr))
+	}
+	return strings.TrimSpace(result.Stdout), nil
+}
+
+// Register registers collectors, a collector registered later takes precedence over an earlier one with the same name
+func (r *Registry) Register(collectors ...*Collector) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.collectors = append(r.collectors, collectors...)
+}
+
+// Collectors returns collectors supporting target, one per name
+func (r *Registry) Collectors(target *Target) []*Collector {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	var result []*Collector
+	selected := map[string]bool{}
+	for i := len(r.collectors) - 1; i >= 0; i-- {
+		collector := r.collectors[i]
+		if selected[collector.Name] || !collector.Supports(target) {
+			continue
+		}
+		selected[collector.Name] = true
+		result = append([]*Collector{collector}, result...)
+	}
+	return result
+}
+
+// Collect collects facts with supported collectors, collector errors are reported in Facts.Errors
+func (r *Registry) Collect(ctx context.Context, aRunner runner.Runner, target *Target) (*Facts, error) {
+	session := &Session{Runner: aRunner, Target: target}
+	ret := &Facts{}
+	for _, collector := range r.Collectors(target) {
+		if err := ctx.Err(); err != nil {
+			return ret, err
+		}
+		if err := collector.Collect(ctx, session, ret); err != nil {
+			if ret.Errors == nil {
+				ret.Errors = map[string]string{}
+			}
+			ret.Errors[collector.Name] = err.Error()
+		}
+	}
+	ret.CollectedAt = time.Now()
+	return ret, nil
+}
+
+// NewRegistry creates a registry with supplied collectors
+func NewRegistry(collectors ...*Collector) *Registry {
+	ret := &Registry{}
+	ret.Register(collectors...)
+	return ret
+}
+
+// New creates a registry with built-in collectors
+func New() *Registry {
+	return NewRegistry(builtins()...)
+}
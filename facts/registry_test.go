@@ -0,0 +1,135 @@
+package facts_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/gosh/facts"
+	"github.com/viant/gosh/runner"
+	"github.com/viant/gosh/runner/replay"
+)
+
+func TestRegistry_Collect(t *testing.T) {
+	var testCases = []struct {
+		description string
+		target      *facts.Target
+		outputs     map[string]string
+		expect      *facts.Facts
+	}{
+		{
+			description: "linux",
+			target:      &facts.Target{System: "linux", Dialect: facts.DialectPosix},
+			outputs: map[string]string{
+				"cat /proc/cpuinfo": `processor	: 0
+model name	: Intel(R) Xeon(R) CPU @ 2.20GHz
+
+processor	: 1
+model name	: Intel(R) Xeon(R) CPU @ 2.20GHz`,
+				"cat /proc/meminfo": `MemTotal:        8000000 kB
+MemFree:          100000 kB
+MemAvailable:    4000000 kB`,
+				"df -P -k": `Filesystem     1024-blocks    Used Available Capacity Mounted on
+/dev/root         10000000 4000000   6000000      40% /
+tmpfs                 1000       0      1000       0% /mnt/my disk`,
+				"cat /proc/mounts": `/dev/root / ext4 rw,relatime 0 0
+tmpfs /mnt/my\040disk tmpfs rw 0 0`,
+				"hostname":                      "web1",
+				"hostname -f 2>/dev/null":       "web1.example.com",
+				"date +'%Z %z'":                 "CET +0100",
+				"cat /etc/timezone 2>/dev/null": "Europe/Warsaw",
+				"cat /etc/shells": `# /etc/shells: valid login shells
+/bin/sh
+/bin/bash`,
+			},
+			expect: &facts.Facts{
+				CPU:    &facts.CPU{Count: 2, Model: "Intel(R) Xeon(R) CPU @ 2.20GHz"},
+				Memory: &facts.Memory{TotalBytes: 8000000 * 1024, AvailableBytes: 4000000 * 1024},
+				Disks: []*facts.Disk{
+					{Device: "/dev/root", MountPoint: "/", FSType: "ext4", SizeBytes: 10000000 * 1024, UsedBytes: 4000000 * 1024, AvailableBytes: 6000000 * 1024},
+					{Device: "tmpfs", MountPoint: "/mnt/my disk", FSType: "tmpfs", SizeBytes: 1000 * 1024, AvailableBytes: 1000 * 1024},
+				},
+				Hostname: "web1",
+				FQDN:     "web1.example.com",
+				Timezone: &facts.Timezone{Name: "Europe/Warsaw", Abbreviation: "CET", Offset: "+0100"},
+				Shells:   []string{"/bin/sh", "/bin/bash"},
+			},
+		},
+		{
+			description: "darwin",
+			target:      &facts.Target{System: "darwin", Dialect: facts.DialectPosix},
+			outputs: map[string]string{
+				"sysctl -n hw.ncpu":                  "10",
+				"sysctl -n machdep.cpu.brand_string": "Apple M1 Pro",
+				"sysctl -n hw.memsize":               "17179869184",
+				"vm_stat": `Mach Virtual Memory Statistics: (page size of 16384 bytes)
+Pages free:                               10.
+Pages active:                            100.
+Pages inactive:                           20.
+Pages speculative:                         2.`,
+				"df -P -k": `Filesystem     1024-blocks      Used Available Capacity  Mounted on
+/dev/disk3s1s1   971350180  10000000 500000000     2%    /`,
+				"mount":                   "/dev/disk3s1s1 on / (apfs, sealed, local, read-only, journaled)",
+				"hostname":                "mbp.local",
+				"date +'%Z %z'":           "PDT -0700",
+				"readlink /etc/localtime": "/var/db/timezone/zoneinfo/America/Los_Angeles",
+				"cat /etc/shells":         "/bin/zsh",
+			},
+			expect: &facts.Facts{
+				CPU:    &facts.CPU{Count: 10, Model: "Apple M1 Pro"},
+				Memory: &facts.Memory{TotalBytes: 17179869184, AvailableBytes: 32 * 16384},
+				Disks: []*facts.Disk{
+					{Device: "/dev/disk3s1s1", MountPoint: "/", FSType: "apfs", SizeBytes: 971350180 * 1024, UsedBytes: 10000000 * 1024, AvailableBytes: 500000000 * 1024},
+				},
+				Hostname: "mbp.local",
+				FQDN:     "mbp.local",
+				Timezone: &facts.Timezone{Name: "America/Los_Angeles", Abbreviation: "PDT", Offset: "-0700"},
+				Shells:   []string{"/bin/zsh"},
+			},
+		},
+		{
+			description: "unsupported dialect",
+			target:      &facts.Target{System: "windows", Dialect: facts.DialectPowerShell},
+			expect:      &facts.Facts{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		var commands []*runner.Command
+		for command, output := range testCase.outputs {
+			commands = append(commands, runner.NewCommand(command, output, nil))
+		}
+		actual, err := facts.New().Collect(context.Background(), replay.New(1, commands), testCase.target)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		actual.CollectedAt = testCase.expect.CollectedAt
+		actual.Errors = nil
+		assert.EqualValues(t, testCase.expect, actual, testCase.description)
+	}
+}
+
+func TestRegistry_Register(t *testing.T) {
+	registry := facts.New()
+	registry.Register(
+		&facts.Collector{Name: facts.CollectorHostname, Collect: func(ctx context.Context, session *facts.Session, facts *facts.Facts) error {
+			facts.Hostname = "override"
+			return nil
+		}},
+		&facts.Collector{Name: "uptime", Systems: []string{"linux"}, Collect: func(ctx context.Context, session *facts.Session, facts *facts.Facts) error {
+			uptime, err := session.Output(ctx, "cat /proc/uptime")
+			facts.Set("uptime", uptime)
+			return err
+		}},
+	)
+	commands := []*runner.Command{runner.NewCommand("cat /proc/uptime", "350735.47 234388.90", nil)}
+	actual, err := registry.Collect(context.Background(), replay.New(1, commands), &facts.Target{System: "linux", Dialect: facts.DialectPosix})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "override", actual.Hostname)
+	assert.EqualValues(t, "350735.47 234388.90", actual.Values["uptime"])
+	assert.Contains(t, actual.Errors, facts.CollectorCPU)
+
+	actual, err = registry.Collect(context.Background(), replay.New(1, nil), &facts.Target{System: "darwin", Dialect: facts.DialectPosix})
+	assert.Nil(t, err)
+	assert.NotContains(t, actual.Values, "uptime")
+}
@@ -0,0 +1,35 @@
+package gosh_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/gosh"
+	"github.com/viant/gosh/runner"
+	"github.com/viant/gosh/runner/replay"
+)
+
+func TestService_Facts(t *testing.T) {
+	commands := []*runner.Command{
+		runner.NewCommand("uname -s", "Linux", nil),
+		runner.NewCommand("hostname", "web1", nil),
+		runner.NewCommand("hostname", "web2", nil),
+	}
+	srv, err := gosh.New(context.Background(), replay.New(1, commands))
+	if !assert.Nil(t, err) {
+		return
+	}
+	hostFacts, err := srv.Facts(context.Background())
+	assert.Nil(t, err)
+	assert.EqualValues(t, "web1", hostFacts.Hostname)
+	cached, err := srv.Facts(context.Background())
+	assert.Nil(t, err)
+	assert.Same(t, hostFacts, cached)
+
+	refreshed, err := srv.RefreshFacts(context.Background())
+	assert.Nil(t, err)
+	assert.EqualValues(t, "web2", refreshed.Hostname)
+	cached, _ = srv.Facts(context.Background())
+	assert.Same(t, refreshed, cached)
+}
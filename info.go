@@ -3,18 +3,20 @@ package gosh
 type (
 	// OSInfo represents operating system details
 	OSInfo struct {
-		System        string // Operating system type
-		Name          string // Name of the OS
-		DistributorID string // ID of the distributor (from lsb_release -a)
-		Description   string // Full description of the OS (from lsb_release -a)
-		Release       string // OS release number (from lsb_release -a)
-		Codename      string // OS release codename (from lsb_release -a)
+		System        string   // Operating system type (uname -s)
+		Name          string   // Name of the OS (os-release NAME, sw_vers ProductName)
+		DistributorID string   // ID of the distributor (os-release ID, lsb_release Distributor ID)
+		IDLike        []string `json:",omitempty"` // IDs of related distributions (os-release ID_LIKE)
+		Description   string   // Full description of the OS (os-release PRETTY_NAME)
+		Release       string   // OS release number (os-release VERSION_ID, sw_vers ProductVersion)
+		Codename      string   // OS release codename (os-release VERSION_CODENAME)
+		Kernel        string   // Kernel version (uname -r)
 	}
 	// HardwareInfo represents hardware details
 	HardwareInfo struct {
 		Hardware     string // Hardware details
 		Architecture string // Full architecture name
 		Arch         string // Architecture abbreviation, typically from uname -m
-		Version      string // Hardware version
+		Version      string // Hardware version (DMI product version, macOS hw.model)
 	}
 )
@@ -29,3 +29,23 @@ func TestRegisterGroup(t *testing.T) {
 		t.Fatalf("timeout waiting for process group completion")
 	}
 }
+
+// TestExitStatus ensures that the status of a reaped group leader is kept.
+func TestExitStatus(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "exit 3")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start command: %v", err)
+	}
+
+	select {
+	case <-RegisterGroup(cmd.Process.Pid):
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timeout waiting for process group completion")
+	}
+	code, ok := ExitStatus(cmd.Process.Pid)
+	if !ok || code != 3 {
+		t.Fatalf("expected exit status 3, got %v (%v)", code, ok)
+	}
+}
@@ -21,6 +21,8 @@ type group struct {
 var (
 	groups   = make(map[int]*group)
 	groupsMu sync.Mutex
+	// statuses keeps wait statuses of reaped group members until ExitStatus is called.
+	statuses = make(map[int]syscall.WaitStatus)
 
 	once sync.Once
 )
@@ -55,30 +57,29 @@ func initListener() {
 		signal.Notify(sigCh, syscall.SIGCHLD)
 
 		go func() {
-			var ws syscall.WaitStatus
 			for {
 				<-sigCh // wait for a signal
-
-				// Drain all exited children.
-				for {
-					pid, err := syscall.Wait4(-1, &ws, syscall.WNOHANG, nil)
-					if pid <= 0 || err == syscall.ECHILD {
-						break
-					}
-				}
-
-				groupsMu.Lock()
-				for pgid, g := range groups {
-					if !processGroupAlive(pgid) {
-						finishGroup(g)
-					}
-				}
-				groupsMu.Unlock()
+				Check()
 			}
 		}()
 	})
 }
 
+// Check checks whether registered process groups are still alive. Members that
+// are not children of this process do not raise SIGCHLD, callers that learn
+// about their exit otherwise (e.g. closed output pipes) can use it instead of
+// waiting for the next poll.
+func Check() {
+	groupsMu.Lock()
+	defer groupsMu.Unlock()
+	for pgid, g := range groups {
+		reapGroup(pgid)
+		if !processGroupAlive(pgid) {
+			finishGroup(g)
+		}
+	}
+}
+
 // pollGroup periodically checks liveness as a safety-net in case signals are
 // missed or the platform does not deliver them (e.g., within some containers).
 // It stops automatically once the group is finished.
@@ -91,12 +92,14 @@ func pollGroup(g *group) {
 
 	for {
 		<-timer.C
+		groupsMu.Lock()
+		reapGroup(g.pgid)
 		if !processGroupAlive(g.pgid) {
-			groupsMu.Lock()
 			finishGroup(g)
 			groupsMu.Unlock()
 			return
 		}
+		groupsMu.Unlock()
 
 		// Exponential back-off up to max.
 		if interval < max {
@@ -109,6 +112,37 @@ func pollGroup(g *group) {
 	}
 }
 
+// reapGroup reaps exited children that belong to the group, so that zombies do
+// not keep it alive, and records their statuses. Only the group members that are
+// children of this process are reaped, other children are left to their owners.
+// Caller must hold groupsMu.
+func reapGroup(pgid int) {
+	var ws syscall.WaitStatus
+	for {
+		pid, err := syscall.Wait4(-pgid, &ws, syscall.WNOHANG, nil)
+		if pid <= 0 || err != nil {
+			return
+		}
+		statuses[pid] = ws
+	}
+}
+
+// ExitStatus returns the exit status of a reaped group member and forgets it,
+// a process killed by a signal reports 128 + signal number like POSIX shells.
+func ExitStatus(pid int) (int, bool) {
+	groupsMu.Lock()
+	defer groupsMu.Unlock()
+	ws, ok := statuses[pid]
+	if !ok {
+		return 0, false
+	}
+	delete(statuses, pid)
+	if ws.Signaled() {
+		return 128 + int(ws.Signal()), true
+	}
+	return ws.ExitStatus(), true
+}
+
 // finishGroup closes the done channel exactly once and removes the group from
 // the registry. Caller must hold groupsMu.
 func finishGroup(g *group) {
@@ -0,0 +1,110 @@
+package mcp
+
+import (
+	"github.com/viant/gosh"
+)
+
+type (
+	// OpenInput represents open session input
+	OpenInput struct {
+		ID             string            `json:"id,omitempty" jsonschema:"optional session id, generated when empty"`
+		Host           string            `json:"host,omitempty" jsonschema:"ssh host[:port], local shell is used when empty"`
+		Username       string            `json:"username,omitempty" jsonschema:"ssh user name"`
+		PrivateKeyPath string            `json:"privateKeyPath,omitempty" jsonschema:"ssh private key location"`
+		Secret         string            `json:"secret,omitempty" jsonschema:"scy encoded ssh credentials resource (URL|key)"`
+		Shell          string            `json:"shell,omitempty" jsonschema:"shell to start, defaults to /bin/sh"`
+		Path           string            `json:"path,omitempty" jsonschema:"initial working directory"`
+		Env            map[string]string `json:"env,omitempty" jsonschema:"environment variables"`
+	}
+
+	// OpenOutput represents open session output
+	OpenOutput struct {
+		ID           string             `json:"id"`
+		User         string             `json:"user,omitempty"`
+		PID          int                `json:"pid,omitempty"`
+		OSInfo       *gosh.OSInfo       `json:"osInfo,omitempty"`
+		HardwareInfo *gosh.HardwareInfo `json:"hardwareInfo,omitempty"`
+	}
+
+	// RunInput represents run command input
+	RunInput struct {
+		SessionID string `json:"sessionId" jsonschema:"session id returned by open"`
+		Command   string `json:"command" jsonschema:"shell command to run"`
+		TimeoutMs int    `json:"timeoutMs,omitempty" jsonschema:"command timeout in milliseconds"`
+	}
+
+	// RunOutput represents run command output
+	RunOutput struct {
+		Stdout     string `json:"stdout,omitempty"`
+		Stderr     string `json:"stderr,omitempty"`
+		ExitCode   int    `json:"exitCode"`
+		StartedAt  string `json:"startedAt,omitempty"`
+		DurationMs int64  `json:"durationMs"`
+		Truncated  bool   `json:"truncated,omitempty"`
+		Error      string `json:"error,omitempty"`
+	}
+
+	// SendInput represents send stdin input
+	SendInput struct {
+		SessionID string `json:"sessionId" jsonschema:"session id returned by open"`
+		Data      string `json:"data" jsonschema:"data to write to the shell stdin"`
+		Newline   bool   `json:"newline,omitempty" jsonschema:"append a line break to data"`
+	}
+
+	// SendOutput represents send stdin output
+	SendOutput struct {
+		Bytes int `json:"bytes"`
+	}
+
+	// HistoryInput represents history input
+	HistoryInput struct {
+		SessionID     string `json:"sessionId" jsonschema:"session id returned by open"`
+		Last          int    `json:"last,omitempty" jsonschema:"number of most recent matching commands to return, from and limit are ignored"`
+		From          int    `json:"from,omitempty" jsonschema:"lowest command index to return, next of the previous page"`
+		Limit         int    `json:"limit,omitempty" jsonschema:"maximum number of commands to return, all when zero"`
+		ExitCode      *int   `json:"exitCode,omitempty" jsonschema:"only commands that exited with this code"`
+		Pattern       string `json:"pattern,omitempty" jsonschema:"regular expression the command has to match"`
+		OutputPattern string `json:"outputPattern,omitempty" jsonschema:"regular expression a line of stdout, stderr or error has to match"`
+		Since         string `json:"since,omitempty" jsonschema:"only commands started at or after this RFC3339 time"`
+		Until         string `json:"until,omitempty" jsonschema:"only commands started before this RFC3339 time"`
+	}
+
+	// HistoryOutput represents history output
+	HistoryOutput struct {
+		Commands []*HistoryEntry `json:"commands"`
+		Next     int             `json:"next"`
+		More     bool            `json:"more,omitempty"`
+	}
+
+	// HistoryEntry represents a history command entry
+	HistoryEntry struct {
+		Index      int    `json:"index"`
+		Command    string `json:"command"`
+		Stdout     string `json:"stdout,omitempty"`
+		Stderr     string `json:"stderr,omitempty"`
+		Error      string `json:"error,omitempty"`
+		ExitCode   int    `json:"exitCode"`
+		Host       string `json:"host,omitempty"`
+		Dir        string `json:"dir,omitempty"`
+		StartedAt  string `json:"startedAt,omitempty"`
+		FinishedAt string `json:"finishedAt,omitempty"`
+		DurationMs int64  `json:"durationMs"`
+	}
+
+	// SessionInput represents a session scoped input
+	SessionInput struct {
+		SessionID string `json:"sessionId" jsonschema:"session id returned by open"`
+	}
+
+	// InfoOutput represents system info output
+	InfoOutput struct {
+		User         string             `json:"user,omitempty"`
+		OSInfo       *gosh.OSInfo       `json:"osInfo,omitempty"`
+		HardwareInfo *gosh.HardwareInfo `json:"hardwareInfo,omitempty"`
+	}
+
+	// CloseOutput represents close session output
+	CloseOutput struct {
+		Closed bool `json:"closed"`
+	}
+)
@@ -0,0 +1,46 @@
+package mcp
+
+import (
+	"context"
+
+	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Register registers shell tools backed by service with MCP server
+func Register(server *sdk.Server, service *Service) {
+	sdk.AddTool(server, &sdk.Tool{
+		Name:        "open_session",
+		Description: "Opens a local or ssh shell session; cd and environment changes persist across calls using the same session id",
+	}, handler(service.Open))
+	sdk.AddTool(server, &sdk.Tool{
+		Name:        "run_command",
+		Description: "Runs a shell command in a session and returns stdout, stderr, exit code and timing; output read before a timeout is returned with the error",
+	}, handler(service.Run))
+	sdk.AddTool(server, &sdk.Tool{
+		Name:        "send_input",
+		Description: "Writes data to the session shell stdin, e.g. to answer an interactive prompt",
+	}, handler(service.Send))
+	sdk.AddTool(server, &sdk.Tool{
+		Name:        "command_history",
+		Description: "Returns commands run in a session with their output and exit codes, filtered by exit code, command or output pattern and time range, paged by the next index",
+	}, handler(service.History))
+	sdk.AddTool(server, &sdk.Tool{
+		Name:        "system_info",
+		Description: "Returns session user, operating system and hardware information",
+	}, handler(service.Info))
+	sdk.AddTool(server, &sdk.Tool{
+		Name:        "close_session",
+		Description: "Closes a shell session",
+	}, handler(service.CloseSession))
+}
+
+// handler adapts a service method to MCP tool handler, output returned with an error is sent as an error result
+func handler[I, O any](fn func(ctx context.Context, input *I) (*O, error)) sdk.ToolHandlerFor[I, *O] {
+	return func(ctx context.Context, request *sdk.CallToolRequest, input I) (*sdk.CallToolResult, *O, error) {
+		output, err := fn(ctx, &input)
+		if err != nil && output != nil {
+			return &sdk.CallToolResult{IsError: true}, output, nil
+		}
+		return nil, output, err
+	}
+}
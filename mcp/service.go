@@ -0,0 +1,299 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/viant/gosh"
+	"github.com/viant/gosh/runner"
+	"github.com/viant/gosh/runner/local"
+	"github.com/viant/gosh/runner/ssh"
+	"github.com/viant/scy"
+	"github.com/viant/scy/cred"
+)
+
+const (
+	defaultSSHPort = "22"
+	// historyCapacity is the number of commands a session keeps for command_history
+	historyCapacity = 1000
+)
+
+type (
+	// Service represents MCP shell service, it keeps sessions keyed by id so that
+	// working directory and environment changes survive between tool calls
+	Service struct {
+		mux         sync.RWMutex
+		sessions    map[string]*session
+		counter     int64
+		middlewares []runner.Middleware
+		sendHooks   []runner.SendHook
+	}
+
+	// Option represents a service option
+	Option func(s *Service)
+
+	session struct {
+		id      string
+		service *gosh.Service
+		history *runner.History
+	}
+)
+
+// Open opens a local or ssh session
+func (s *Service) Open(ctx context.Context, input *OpenInput) (*OpenOutput, error) {
+	id := input.ID
+	if id == "" {
+		id = "session-" + strconv.FormatInt(atomic.AddInt64(&s.counter, 1), 10)
+	}
+	s.mux.RLock()
+	_, ok := s.sessions[id]
+	s.mux.RUnlock()
+	if ok {
+		return nil, fmt.Errorf("session %v already exists", id)
+	}
+	history := runner.NewHistory(runner.WithHistoryCapacity(historyCapacity))
+	options := []runner.Option{runner.WithHistory(history)}
+	if input.Shell != "" {
+		options = append(options, runner.WithShell(input.Shell))
+	}
+	if input.Path != "" {
+		options = append(options, runner.WithPath(input.Path))
+	}
+	if len(input.Env) > 0 {
+		options = append(options, runner.WithEnvironment(input.Env))
+	}
+	aRunner, err := s.newRunner(ctx, input, options)
+	if err != nil {
+		return nil, err
+	}
+	srv, err := gosh.New(ctx, aRunner, gosh.WithMiddleware(s.middlewares...), gosh.WithSendHook(s.sendHooks...))
+	if err != nil {
+		_ = aRunner.Close()
+		return nil, fmt.Errorf("failed to open session %v: %w", id, err)
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if _, ok := s.sessions[id]; ok {
+		_ = srv.Close()
+		return nil, fmt.Errorf("session %v already exists", id)
+	}
+	s.sessions[id] = &session{id: id, service: srv, history: history}
+	return &OpenOutput{ID: id, User: srv.User(), PID: srv.PID(), OSInfo: srv.OsInfo(), HardwareInfo: srv.HardwareInfo()}, nil
+}
+
+func (s *Service) newRunner(ctx context.Context, input *OpenInput, options []runner.Option) (runner.Runner, error) {
+	if input.Host == "" {
+		return local.New(options...), nil
+	}
+	sshCred := &cred.SSH{PrivateKeyPath: input.PrivateKeyPath, Basic: cred.Basic{Username: input.Username}}
+	if input.Secret != "" {
+		resource := scy.EncodedResource(input.Secret).Decode(ctx, sshCred)
+		secret, err := scy.New().Load(ctx, resource)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load secret: %w", err)
+		}
+		loaded, ok := secret.Target.(*cred.SSH)
+		if !ok {
+			return nil, fmt.Errorf("unsupported secret type: %T", secret.Target)
+		}
+		sshCred = loaded
+		if sshCred.Username == "" {
+			sshCred.Username = input.Username
+		}
+	}
+	config, err := sshCred.Config(ctx)
+	if err != nil {
+		return nil, err
+	}
+	host := input.Host
+	if !strings.Contains(host, ":") {
+		host += ":" + defaultSSHPort
+	}
+	return ssh.New(host, config, options...), nil
+}
+
+// Run runs a command in a session
+func (s *Service) Run(ctx context.Context, input *RunInput) (*RunOutput, error) {
+	sess, err := s.session(input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	var options []runner.Option
+	if input.TimeoutMs > 0 {
+		options = append(options, runner.WithTimeout(input.TimeoutMs))
+	}
+	result, err := sess.service.RunResult(ctx, input.Command, options...)
+	if result == nil {
+		return nil, err
+	}
+	output := &RunOutput{
+		Stdout:     result.Stdout,
+		Stderr:     result.Stderr,
+		ExitCode:   result.ExitCode,
+		StartedAt:  formatTime(result.StartedAt),
+		DurationMs: result.Duration.Milliseconds(),
+		Truncated:  result.Truncated,
+	}
+	if err != nil { // output read before a timeout or an interruption is returned with the error
+		output.Error = err.Error()
+	}
+	return output, err
+}
+
+// Send sends data to session stdin
+func (s *Service) Send(ctx context.Context, input *SendInput) (*SendOutput, error) {
+	sess, err := s.session(input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	data := input.Data
+	if input.Newline {
+		data = runner.EnsureLineTermination(data)
+	}
+	n, err := sess.service.Send(ctx, []byte(data))
+	if err != nil {
+		return nil, err
+	}
+	return &SendOutput{Bytes: n}, nil
+}
+
+// History returns session command history matching the input, a page is continued from its next index
+func (s *Service) History(ctx context.Context, input *HistoryInput) (*HistoryOutput, error) {
+	sess, err := s.session(input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	query, err := historyQuery(input)
+	if err != nil {
+		return nil, err
+	}
+	page := sess.history.Query(query)
+	output := &HistoryOutput{Commands: make([]*HistoryEntry, 0, len(page.Commands)), Next: page.Next, More: page.More}
+	for _, command := range page.Commands {
+		entry := &HistoryEntry{
+			Index:      command.Index,
+			Command:    command.Stdin,
+			Stdout:     command.Output(),
+			Stderr:     strings.Join(command.Stderr, "\n"),
+			ExitCode:   command.ExitCode,
+			Host:       command.Host,
+			Dir:        command.Dir,
+			StartedAt:  formatTime(command.StartedAt),
+			FinishedAt: formatTime(command.FinishedAt),
+			DurationMs: command.Duration.Milliseconds(),
+		}
+		if err := command.Err(); err != nil {
+			entry.Error = err.Error()
+		}
+		output.Commands = append(output.Commands, entry)
+	}
+	return output, nil
+}
+
+// Info returns session system info
+func (s *Service) Info(ctx context.Context, input *SessionInput) (*InfoOutput, error) {
+	sess, err := s.session(input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &InfoOutput{User: sess.service.User(), OSInfo: sess.service.OsInfo(), HardwareInfo: sess.service.HardwareInfo()}, nil
+}
+
+// CloseSession closes a session
+func (s *Service) CloseSession(ctx context.Context, input *SessionInput) (*CloseOutput, error) {
+	s.mux.Lock()
+	sess, ok := s.sessions[input.SessionID]
+	delete(s.sessions, input.SessionID)
+	s.mux.Unlock()
+	if !ok {
+		return &CloseOutput{}, nil
+	}
+	return &CloseOutput{Closed: true}, sess.service.Close()
+}
+
+// Close closes all sessions
+func (s *Service) Close() (err error) {
+	s.mux.Lock()
+	sessions := s.sessions
+	s.sessions = map[string]*session{}
+	s.mux.Unlock()
+	for _, sess := range sessions {
+		if e := sess.service.Close(); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (s *Service) session(id string) (*session, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("session %v not found", id)
+	}
+	return sess, nil
+}
+
+// historyQuery returns history query of the input
+func historyQuery(input *HistoryInput) (*runner.HistoryQuery, error) {
+	ret := &runner.HistoryQuery{ExitCode: input.ExitCode, From: input.From, Limit: input.Limit, Last: input.Last}
+	var err error
+	if input.Pattern != "" {
+		if ret.Command, err = regexp.Compile(input.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	if input.OutputPattern != "" {
+		if ret.Output, err = regexp.Compile(input.OutputPattern); err != nil {
+			return nil, fmt.Errorf("invalid outputPattern: %w", err)
+		}
+	}
+	if input.Since != "" {
+		if ret.Since, err = time.Parse(time.RFC3339, input.Since); err != nil {
+			return nil, fmt.Errorf("invalid since: %w", err)
+		}
+	}
+	if input.Until != "" {
+		if ret.Until, err = time.Parse(time.RFC3339, input.Until); err != nil {
+			return nil, fmt.Errorf("invalid until: %w", err)
+		}
+	}
+	return ret, nil
+}
+
+func formatTime(ts time.Time) string {
+	if ts.IsZero() {
+		return ""
+	}
+	return ts.Format(time.RFC3339Nano)
+}
+
+// WithMiddleware runs commands of every session through the middleware, e.g. a command policy
+func WithMiddleware(middlewares ...runner.Middleware) Option {
+	return func(s *Service) {
+		s.middlewares = append(s.middlewares, middlewares...)
+	}
+}
+
+// WithSendHook checks data sent to every session that the shell reads as commands with the hooks, e.g. a command policy
+func WithSendHook(hooks ...runner.SendHook) Option {
+	return func(s *Service) {
+		s.sendHooks = append(s.sendHooks, hooks...)
+	}
+}
+
+// New creates a new MCP shell service
+func New(options ...Option) *Service {
+	ret := &Service{sessions: map[string]*session{}}
+	for _, option := range options {
+		option(ret)
+	}
+	return ret
+}
@@ -0,0 +1,142 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/gosh/policy"
+)
+
+func TestService_Session(t *testing.T) {
+	ctx := context.Background()
+	service := New()
+	defer service.Close()
+
+	opened, err := service.Open(ctx, &OpenInput{ID: "s1"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "s1", opened.ID)
+	assert.NotNil(t, opened.OSInfo)
+
+	_, err = service.Open(ctx, &OpenInput{ID: "s1"})
+	assert.NotNil(t, err)
+
+	_, err = service.Run(ctx, &RunInput{SessionID: "s1", Command: "cd /tmp"})
+	assert.Nil(t, err)
+	output, err := service.Run(ctx, &RunInput{SessionID: "s1", Command: "pwd; ls /no/such/dir"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Contains(t, output.Stdout, "/tmp")
+	assert.NotEqual(t, 0, output.ExitCode)
+
+	history, err := service.History(ctx, &HistoryInput{SessionID: "s1", Last: 1})
+	assert.Nil(t, err)
+	if assert.Len(t, history.Commands, 1) {
+		assert.Equal(t, "pwd; ls /no/such/dir", history.Commands[0].Command)
+		assert.Equal(t, "/tmp", history.Commands[0].Dir)
+	}
+	page, err := service.History(ctx, &HistoryInput{SessionID: "s1", Pattern: "^(cd|pwd)", Limit: 1})
+	if assert.Nil(t, err) && assert.Len(t, page.Commands, 1) {
+		assert.Equal(t, "cd /tmp", page.Commands[0].Command)
+		assert.True(t, page.More)
+		page, err = service.History(ctx, &HistoryInput{SessionID: "s1", Pattern: "^(cd|pwd)", Limit: 1, From: page.Next})
+		assert.Nil(t, err)
+		assert.Len(t, page.Commands, 1)
+		assert.False(t, page.More)
+	}
+	_, err = service.History(ctx, &HistoryInput{SessionID: "s1", OutputPattern: "("})
+	assert.NotNil(t, err)
+
+	output, err = service.Run(ctx, &RunInput{SessionID: "s1", Command: "echo partial; sleep 2", TimeoutMs: 300})
+	assert.NotNil(t, err)
+	if assert.NotNil(t, output) {
+		assert.Contains(t, output.Stdout, "partial")
+		assert.Equal(t, err.Error(), output.Error)
+	}
+
+	closed, err := service.CloseSession(ctx, &SessionInput{SessionID: "s1"})
+	assert.Nil(t, err)
+	assert.True(t, closed.Closed)
+	_, err = service.Run(ctx, &RunInput{SessionID: "s1", Command: "pwd"})
+	assert.NotNil(t, err)
+}
+
+func TestService_Policy(t *testing.T) {
+	ctx := context.Background()
+	commandPolicy := &policy.Policy{
+		Rules:   []*policy.Rule{{Action: policy.Allow, Commands: []string{"echo"}}},
+		Default: policy.Approve,
+	}
+	service := New(WithMiddleware(commandPolicy.Middleware(nil)), WithSendHook(commandPolicy.SendHook(nil)))
+	defer service.Close()
+	opened, err := service.Open(ctx, &OpenInput{ID: "restricted"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.NotEmpty(t, opened.OSInfo.System)
+
+	output, err := service.Run(ctx, &RunInput{SessionID: "restricted", Command: "echo allowed"})
+	if assert.Nil(t, err) {
+		assert.Contains(t, output.Stdout, "allowed")
+	}
+	_, err = service.Run(ctx, &RunInput{SessionID: "restricted", Command: "uname -s"})
+	assert.ErrorIs(t, err, policy.ErrApprovalRequired)
+	_, err = service.Send(ctx, &SendInput{SessionID: "restricted", Data: "una"})
+	assert.Nil(t, err)
+	_, err = service.Send(ctx, &SendInput{SessionID: "restricted", Data: "me -s", Newline: true})
+	assert.ErrorIs(t, err, policy.ErrApprovalRequired)
+}
+
+func TestRegister(t *testing.T) {
+	ctx := context.Background()
+	service := New()
+	defer service.Close()
+	server := sdk.NewServer(&sdk.Implementation{Name: "gosh", Version: "test"}, nil)
+	Register(server, service)
+
+	serverTransport, clientTransport := sdk.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer serverSession.Close()
+	client := sdk.NewClient(&sdk.Implementation{Name: "client", Version: "test"}, nil)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer clientSession.Close()
+
+	_, err = clientSession.CallTool(ctx, &sdk.CallToolParams{Name: "open_session", Arguments: map[string]interface{}{"id": "agent"}})
+	if !assert.Nil(t, err) {
+		return
+	}
+	result, err := clientSession.CallTool(ctx, &sdk.CallToolParams{Name: "run_command", Arguments: map[string]interface{}{"sessionId": "agent", "command": "echo hello"}})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.False(t, result.IsError)
+	data, err := json.Marshal(result.StructuredContent)
+	assert.Nil(t, err)
+	output := &RunOutput{}
+	assert.Nil(t, json.Unmarshal(data, output))
+	assert.Contains(t, output.Stdout, "hello")
+	assert.Equal(t, 0, output.ExitCode)
+
+	result, err = clientSession.CallTool(ctx, &sdk.CallToolParams{Name: "run_command", Arguments: map[string]interface{}{"sessionId": "agent", "command": "echo partial; sleep 2", "timeoutMs": 300}})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, result.IsError)
+	data, err = json.Marshal(result.StructuredContent)
+	assert.Nil(t, err)
+	output = &RunOutput{}
+	assert.Nil(t, json.Unmarshal(data, output))
+	assert.Contains(t, output.Stdout, "partial")
+	assert.NotEmpty(t, output.Error)
+}
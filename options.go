@@ -0,0 +1,37 @@
+package gosh
+
+import (
+	"github.com/viant/gosh/facts"
+	"github.com/viant/gosh/runner"
+)
+
+// Option represents a service option
+type Option func(s *Service)
+
+// WithFacts sets facts collector registry, built-in collectors are used by default
+func WithFacts(registry *facts.Registry) Option {
+	return func(s *Service) {
+		s.facts = registry
+	}
+}
+
+// WithDialect sets shell dialect used to select facts collectors, facts.DialectPosix by default
+func WithDialect(dialect string) Option {
+	return func(s *Service) {
+		s.dialect = dialect
+	}
+}
+
+// WithMiddleware runs service commands through the middleware, system detection runs on the runner directly, see runner.Wrap
+func WithMiddleware(middlewares ...runner.Middleware) Option {
+	return func(s *Service) {
+		s.middlewares = append(s.middlewares, middlewares...)
+	}
+}
+
+// WithSendHook checks data sent with Send that the shell reads as commands with the hooks, see runner.WrapSend
+func WithSendHook(hooks ...runner.SendHook) Option {
+	return func(s *Service) {
+		s.sendHooks = append(s.sendHooks, hooks...)
+	}
+}
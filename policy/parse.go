@@ -0,0 +1,411 @@
+package policy
+
+import (
+	"bytes"
+	"path"
+	"slices"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+type (
+	// Word represents a command word, Value of a word that is not literal, e.g. with parameter expansion,
+	// command substitution, globbing or brace expansion, is its source text
+	Word struct {
+		Value   string
+		Literal bool
+	}
+
+	// Redirect represents a redirection of a command, including redirections of enclosing compound commands
+	Redirect struct {
+		Op     string
+		Target Word
+	}
+
+	// Invocation represents a simple command found in the parsed command, commands run by known wrappers such as sudo, env,
+	// xargs, busybox, ssh or find -exec and by nested scripts of sh -c, su -c and eval are invocations too
+	Invocation struct {
+		Name      Word
+		Args      []Word
+		Redirects []*Redirect
+		Subshell  bool // runs in a subshell, command or process substitution or a nested script
+	}
+
+	// wrapper describes a command running another command given as its operands
+	wrapper struct {
+		valueOptions string   // short options taking a value
+		operands     int      // operands preceding the command, e.g. the duration of timeout
+		assignments  bool     // NAME=value operands preceding the command are skipped
+		script       bool     // the command words are joined into a script run by a shell, e.g. by ssh or watch
+		scripts      string   // short options taking a script run by a shell, e.g. -c of su
+		longScripts  []string // long options taking a script run by a shell, e.g. --command of su
+		noCommand    bool     // operands are not a command, only script options run one
+	}
+
+	// extractor collects invocations of a syntax tree
+	extractor struct {
+		parser      *syntax.Parser
+		invocations []*Invocation
+		redirects   []*Redirect // redirections of enclosing statements
+		nodes       []syntax.Node
+		subshell    int
+	}
+)
+
+// wrappers are commands running the command given as their operands, name based rules do not match commands run by
+// wrappers that are not listed
+var wrappers = map[string]*wrapper{
+	"sudo":        {valueOptions: "CDghprtUu"},
+	"doas":        {valueOptions: "Cu"},
+	"su":          {valueOptions: "gGsw", scripts: "c", longScripts: []string{"--command"}, noCommand: true},
+	"runuser":     {valueOptions: "gGsuw", scripts: "c", longScripts: []string{"--command"}, noCommand: true},
+	"env":         {valueOptions: "uC", assignments: true, scripts: "S", longScripts: []string{"--split-string"}},
+	"nice":        {valueOptions: "n"},
+	"ionice":      {valueOptions: "cnp"},
+	"timeout":     {valueOptions: "ks", operands: 1},
+	"chroot":      {operands: 1},
+	"xargs":       {valueOptions: "adEeIiLlnPs"},
+	"nohup":       {},
+	"exec":        {valueOptions: "a"},
+	"command":     {},
+	"builtin":     {},
+	"time":        {},
+	"stdbuf":      {valueOptions: "ioe"},
+	"setsid":      {},
+	"strace":      {valueOptions: "eoOpPsSuU"},
+	"ltrace":      {valueOptions: "eoOpPsSuU"},
+	"busybox":     {},
+	"unbuffer":    {},
+	"taskset":     {operands: 1},
+	"chrt":        {operands: 1},
+	"unshare":     {valueOptions: "SGRw"},
+	"nsenter":     {valueOptions: "tSG"},
+	"systemd-run": {valueOptions: "uEpMH"},
+	"flock":       {valueOptions: "wE", operands: 1, scripts: "c", longScripts: []string{"--command"}},
+	"watch":       {valueOptions: "nq", script: true},
+	"parallel":    {valueOptions: "jSX", script: true},
+	"ssh":         {valueOptions: "BbcDEeFIiJLlmOoPpQRSWw", operands: 1, script: true},
+}
+
+// shells run a script given with -c
+var shells = map[string]bool{"sh": true, "bash": true, "dash": true, "zsh": true, "ksh": true, "ash": true, "mksh": true}
+
+// String returns the invocation as written, words that are not literal as their source
+func (i *Invocation) String() string {
+	words := []string{i.Name.Value}
+	for _, arg := range i.Args {
+		words = append(words, arg.Value)
+	}
+	return strings.TrimSpace(strings.Join(words, " "))
+}
+
+// Parse returns invocations of a POSIX or bash command
+func Parse(command string) ([]*Invocation, error) {
+	anExtractor := &extractor{parser: syntax.NewParser(syntax.Variant(syntax.LangBash))}
+	if err := anExtractor.script(command); err != nil {
+		return nil, err
+	}
+	return anExtractor.invocations, nil
+}
+
+// incomplete returns true if the shell would read more lines to complete the script, e.g. an unclosed quote,
+// here-document or compound command, or a line continued with a backslash
+func incomplete(script string) bool {
+	body := strings.TrimSuffix(script, "\n")
+	if (len(body)-len(strings.TrimRight(body, "\\")))%2 == 1 {
+		return true
+	}
+	_, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(script), "")
+	return syntax.IsIncomplete(err)
+}
+
+func (e *extractor) script(script string) error {
+	file, err := e.parser.Parse(strings.NewReader(script), "")
+	if err != nil {
+		return err
+	}
+	syntax.Walk(file, e.visit)
+	return nil
+}
+
+func (e *extractor) visit(node syntax.Node) bool {
+	if node == nil {
+		e.leave(e.nodes[len(e.nodes)-1])
+		e.nodes = e.nodes[:len(e.nodes)-1]
+		return true
+	}
+	e.nodes = append(e.nodes, node)
+	switch actual := node.(type) {
+	case *syntax.Stmt:
+		for _, redirect := range actual.Redirs {
+			if aRedirect := redirectOf(redirect); aRedirect != nil {
+				e.redirects = append(e.redirects, aRedirect)
+			}
+		}
+		if actual.Cmd == nil && len(e.redirects) > 0 { // > file
+			e.add(&Invocation{Name: Word{Literal: true}})
+		}
+	case *syntax.Subshell, *syntax.CmdSubst, *syntax.ProcSubst:
+		e.subshell++
+	case *syntax.CallExpr:
+		if len(actual.Args) == 0 {
+			if len(e.redirects) > 0 { // a=b > file
+				e.add(&Invocation{Name: Word{Literal: true}})
+			}
+			return true
+		}
+		words := make([]Word, len(actual.Args))
+		for i, arg := range actual.Args {
+			words[i] = wordOf(arg)
+		}
+		e.call(words)
+	}
+	return true
+}
+
+func (e *extractor) leave(node syntax.Node) {
+	switch actual := node.(type) {
+	case *syntax.Stmt:
+		for _, redirect := range actual.Redirs {
+			if redirectOf(redirect) != nil {
+				e.redirects = e.redirects[:len(e.redirects)-1]
+			}
+		}
+	case *syntax.Subshell, *syntax.CmdSubst, *syntax.ProcSubst:
+		e.subshell--
+	}
+}
+
+func (e *extractor) add(invocation *Invocation) {
+	invocation.Redirects = append([]*Redirect{}, e.redirects...)
+	invocation.Subshell = e.subshell > 0
+	e.invocations = append(e.invocations, invocation)
+}
+
+// call adds invocation of the words and of commands it runs
+func (e *extractor) call(words []Word) {
+	e.add(&Invocation{Name: words[0], Args: words[1:]})
+	if !words[0].Literal {
+		return
+	}
+	name, args := path.Base(words[0].Value), words[1:]
+	switch {
+	case shells[name]:
+		if script, ok := shellScript(args); ok {
+			e.nested(script)
+		}
+	case name == "eval":
+		e.nested(joined(args))
+	case name == "find":
+		for i := 0; i < len(args); i++ {
+			switch args[i].Value {
+			case "-exec", "-execdir", "-ok", "-okdir":
+				end := i + 1
+				for end < len(args) && args[end].Value != ";" && args[end].Value != "+" {
+					end++
+				}
+				if end > i+1 {
+					e.call(args[i+1 : end])
+				}
+				i = end
+			}
+		}
+	case wrappers[name] != nil:
+		aWrapper := wrappers[name]
+		for _, script := range aWrapper.scriptsOf(args) {
+			e.nested(script)
+		}
+		if aWrapper.noCommand {
+			return
+		}
+		rest := aWrapper.command(args)
+		switch {
+		case len(rest) == 0:
+		case aWrapper.script:
+			e.nested(joined(rest))
+		default:
+			e.call(rest)
+		}
+	}
+}
+
+// joined returns words joined into a script, e.g. of eval
+func joined(words []Word) Word {
+	script := Word{Literal: true}
+	for _, word := range words {
+		if word.Value == ":::" || word.Value == "::::" { // parallel arguments follow
+			break
+		}
+		script.Value = strings.TrimSpace(script.Value + " " + word.Value)
+		script.Literal = script.Literal && word.Literal
+	}
+	return script
+}
+
+// nested adds invocations of a nested script, a script that is not literal or can not be parsed is a dynamic invocation
+func (e *extractor) nested(script Word) {
+	e.subshell++
+	defer func() { e.subshell-- }()
+	if script.Literal {
+		nestedExtractor := &extractor{parser: e.parser, redirects: e.redirects, subshell: e.subshell}
+		if err := nestedExtractor.script(script.Value); err == nil {
+			e.invocations = append(e.invocations, nestedExtractor.invocations...)
+			return
+		}
+	}
+	e.add(&Invocation{Name: Word{Value: script.Value}})
+}
+
+// scriptsOf returns values of the wrapper options taking a script
+func (w *wrapper) scriptsOf(args []Word) []Word {
+	var result []Word
+	for i, arg := range args {
+		if arg.Value == "--" {
+			break
+		}
+		for _, option := range w.longScripts {
+			if value, ok := strings.CutPrefix(arg.Value, option+"="); ok {
+				result = append(result, Word{Value: value, Literal: arg.Literal})
+			} else if arg.Value == option && i+1 < len(args) {
+				result = append(result, args[i+1])
+			}
+		}
+		if w.scripts != "" && len(arg.Value) > 1 && arg.Value[0] == '-' && arg.Value[1] != '-' && strings.Contains(w.scripts, arg.Value[len(arg.Value)-1:]) && i+1 < len(args) {
+			result = append(result, args[i+1])
+		}
+	}
+	return result
+}
+
+// command returns words of the command run by the wrapper
+func (w *wrapper) command(args []Word) []Word {
+	operands := w.operands
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case !arg.Literal:
+			return args[i:]
+		case arg.Value == "--":
+			return skip(args[i+1:], operands)
+		case strings.HasPrefix(arg.Value, "--"):
+			if slices.Contains(w.longScripts, arg.Value) {
+				i++
+			}
+		case strings.HasPrefix(arg.Value, "-") && len(arg.Value) > 1:
+			if last := arg.Value[len(arg.Value)-1:]; strings.Contains(w.valueOptions+w.scripts, last) {
+				i++
+			}
+		case w.assignments && strings.Contains(arg.Value, "=") && !strings.HasPrefix(arg.Value, "="):
+		case operands > 0:
+			operands--
+		default:
+			return args[i:]
+		}
+	}
+	return nil
+}
+
+func skip(args []Word, count int) []Word {
+	if count >= len(args) {
+		return nil
+	}
+	return args[count:]
+}
+
+// shellScript returns script of a shell run with -c
+func shellScript(args []Word) (Word, bool) {
+	command := false
+	for _, arg := range args {
+		switch {
+		case !arg.Literal:
+			return arg, command
+		case arg.Value == "--":
+		case strings.HasPrefix(arg.Value, "-") || strings.HasPrefix(arg.Value, "+"):
+			command = command || (!strings.HasPrefix(arg.Value, "--") && strings.Contains(arg.Value, "c"))
+		default:
+			return arg, command
+		}
+	}
+	return Word{}, false
+}
+
+// redirectOf returns redirection to a file, nil for here-documents and file descriptor duplication
+func redirectOf(redirect *syntax.Redirect) *Redirect {
+	switch redirect.Op {
+	case syntax.Hdoc, syntax.DashHdoc, syntax.WordHdoc:
+		return nil
+	}
+	target := wordOf(redirect.Word)
+	if redirect.Op == syntax.DplIn || redirect.Op == syntax.DplOut {
+		if value := strings.TrimSuffix(target.Value, "-"); target.Literal && (value == "" || strings.Trim(value, "0123456789") == "") {
+			return nil
+		}
+	}
+	return &Redirect{Op: redirect.Op.String(), Target: target}
+}
+
+// wordOf returns value of a word with quotes removed if it is literal
+func wordOf(word *syntax.Word) Word {
+	builder := strings.Builder{}
+	literal := true
+	for _, part := range word.Parts {
+		switch actual := part.(type) {
+		case *syntax.Lit:
+			literal = unescape(&builder, actual.Value, false) && literal
+		case *syntax.SglQuoted:
+			literal = literal && !actual.Dollar
+			builder.WriteString(actual.Value)
+		case *syntax.DblQuoted:
+			for _, quoted := range actual.Parts {
+				if lit, ok := quoted.(*syntax.Lit); ok {
+					unescape(&builder, lit.Value, true)
+					continue
+				}
+				literal = false
+			}
+		default:
+			literal = false
+		}
+	}
+	if literal {
+		return Word{Value: builder.String(), Literal: true}
+	}
+	source := bytes.Buffer{}
+	_ = syntax.NewPrinter().Print(&source, word)
+	return Word{Value: source.String()}
+}
+
+// unescape writes text with backslash escapes removed, it returns false if unquoted text has glob or brace expansion
+func unescape(builder *strings.Builder, text string, quoted bool) bool {
+	literal := true
+	bracket := false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c == '\\' && i+1 < len(text) {
+			if next := text[i+1]; !quoted || strings.IndexByte("$`\"\\\n", next) != -1 {
+				if next != '\n' {
+					builder.WriteByte(next)
+				}
+				i++
+				continue
+			}
+		}
+		if !quoted {
+			switch c {
+			case '*', '?':
+				literal = false
+			case '[':
+				bracket = true
+			case ']':
+				literal = literal && !bracket
+			case '{':
+				if strings.ContainsAny(text[i:], ",") || strings.Contains(text[i:], "..") {
+					literal = false
+				}
+			}
+		}
+		builder.WriteByte(c)
+	}
+	return literal
+}
@@ -0,0 +1,72 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	var testCases = []struct {
+		description string
+		command     string
+		expect      []string
+		subshell    []bool
+		literal     []bool
+	}{
+		{description: "simple", command: "ls -la /tmp", expect: []string{"ls -la /tmp"}, subshell: []bool{false}, literal: []bool{true}},
+		{description: "list and pipe", command: "cd /tmp && ls | grep x; pwd", expect: []string{"cd /tmp", "ls", "grep x", "pwd"}, subshell: []bool{false, false, false, false}, literal: []bool{true, true, true, true}},
+		{description: "quotes and escapes", command: `r\m -rf "/"`, expect: []string{"rm -rf /"}, subshell: []bool{false}, literal: []bool{true}},
+		{description: "quote splicing", command: `'r'"m" -rf /`, expect: []string{"rm -rf /"}, subshell: []bool{false}, literal: []bool{true}},
+		{description: "subshell", command: "(rm -rf /)", expect: []string{"rm -rf /"}, subshell: []bool{true}, literal: []bool{true}},
+		{description: "command substitution", command: "echo $(rm -rf /)", expect: []string{"echo $(rm -rf /)", "rm -rf /"}, subshell: []bool{false, true}, literal: []bool{true, true}},
+		{description: "process substitution", command: "diff <(ls a) b", expect: []string{"diff <(ls a) b", "ls a"}, subshell: []bool{false, true}, literal: []bool{true, true}},
+		{description: "variable", command: "$CMD -rf /", expect: []string{"$CMD -rf /"}, subshell: []bool{false}, literal: []bool{false}},
+		{description: "glob", command: "/bin/r? -rf /", expect: []string{"/bin/r? -rf /"}, subshell: []bool{false}, literal: []bool{false}},
+		{description: "brace expansion", command: "{rm,-rf,/}", expect: []string{"{rm,-rf,/}"}, subshell: []bool{false}, literal: []bool{false}},
+		{description: "ansi c quoting", command: `$'\x72m' -rf /`, expect: []string{`$'\x72m' -rf /`}, subshell: []bool{false}, literal: []bool{false}},
+		{description: "sudo", command: "sudo -u root rm -rf /", expect: []string{"sudo -u root rm -rf /", "rm -rf /"}, subshell: []bool{false, false}, literal: []bool{true, true}},
+		{description: "env", command: "env A=1 timeout -s KILL 5 rm -rf /", expect: []string{"env A=1 timeout -s KILL 5 rm -rf /", "timeout -s KILL 5 rm -rf /", "rm -rf /"}, subshell: []bool{false, false, false}, literal: []bool{true, true, true}},
+		{description: "xargs", command: "ls | xargs -n 1 rm", expect: []string{"ls", "xargs -n 1 rm", "rm"}, subshell: []bool{false, false, false}, literal: []bool{true, true, true}},
+		{description: "find exec", command: `find . -name x -exec rm {} \;`, expect: []string{"find . -name x -exec rm {} ;", "rm {}"}, subshell: []bool{false, false}, literal: []bool{true, true}},
+		{description: "shell script", command: `bash -c "rm -rf /"`, expect: []string{"bash -c rm -rf /", "rm -rf /"}, subshell: []bool{false, true}, literal: []bool{true, true}},
+		{description: "eval", command: `eval "rm -rf" /`, expect: []string{"eval rm -rf /", "rm -rf /"}, subshell: []bool{false, true}, literal: []bool{true, true}},
+		{description: "dynamic script", command: `sh -c "$SCRIPT"`, expect: []string{`sh -c "$SCRIPT"`, `"$SCRIPT"`}, subshell: []bool{false, true}, literal: []bool{true, false}},
+		{description: "busybox applet", command: "busybox rm -rf /", expect: []string{"busybox rm -rf /", "rm -rf /"}, subshell: []bool{false, false}, literal: []bool{true, true}},
+		{description: "busybox shell", command: "busybox sh -c 'rm x'", expect: []string{"busybox sh -c rm x", "sh -c rm x", "rm x"}, subshell: []bool{false, false, true}, literal: []bool{true, true, true}},
+		{description: "watch", command: "watch -n 1 rm x", expect: []string{"watch -n 1 rm x", "rm x"}, subshell: []bool{false, true}, literal: []bool{true, true}},
+		{description: "flock", command: "flock -w 5 /tmp/l rm x", expect: []string{"flock -w 5 /tmp/l rm x", "rm x"}, subshell: []bool{false, false}, literal: []bool{true, true}},
+		{description: "flock script", command: "flock /tmp/l -c 'rm x'", expect: []string{"flock /tmp/l -c rm x", "rm x"}, subshell: []bool{false, true}, literal: []bool{true, true}},
+		{description: "parallel", command: "parallel rm ::: a b", expect: []string{"parallel rm ::: a b", "rm"}, subshell: []bool{false, true}, literal: []bool{true, true}},
+		{description: "ssh", command: "ssh -p 22 host rm x", expect: []string{"ssh -p 22 host rm x", "rm x"}, subshell: []bool{false, true}, literal: []bool{true, true}},
+		{description: "su", command: "su - root --command='rm x'", expect: []string{"su - root --command=rm x", "rm x"}, subshell: []bool{false, true}, literal: []bool{true, true}},
+		{description: "redirect only", command: "> /etc/passwd", expect: []string{""}, subshell: []bool{false}, literal: []bool{true}},
+	}
+	for _, testCase := range testCases {
+		invocations, err := Parse(testCase.command)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		var actual []string
+		var subshell, literal []bool
+		for _, invocation := range invocations {
+			actual = append(actual, invocation.String())
+			subshell = append(subshell, invocation.Subshell)
+			literal = append(literal, invocation.Name.Literal)
+		}
+		assert.Equal(t, testCase.expect, actual, testCase.description)
+		assert.Equal(t, testCase.subshell, subshell, testCase.description)
+		assert.Equal(t, testCase.literal, literal, testCase.description)
+	}
+	_, err := Parse("ls (")
+	assert.NotNil(t, err)
+}
+
+func TestParse_Redirects(t *testing.T) {
+	invocations, err := Parse("{ echo a > out.txt 2>&1; cat <<EOF\nx\nEOF\n} >> /var/log/all")
+	if !assert.Nil(t, err) || !assert.Len(t, invocations, 2) {
+		return
+	}
+	assert.Equal(t, []*Redirect{{Op: ">>", Target: Word{Value: "/var/log/all", Literal: true}}, {Op: ">", Target: Word{Value: "out.txt", Literal: true}}}, invocations[0].Redirects)
+	assert.Equal(t, []*Redirect{{Op: ">>", Target: Word{Value: "/var/log/all", Literal: true}}}, invocations[1].Redirects)
+}
@@ -0,0 +1,291 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/viant/gosh/runner"
+)
+
+const (
+	// Allow allows the command
+	Allow = "allow"
+	// Approve requires an approval to run the command
+	Approve = "approve"
+	// Deny denies the command
+	Deny = "deny"
+)
+
+var (
+	// ErrDenied matches errors of commands denied by the policy or not approved
+	ErrDenied = errors.New("command denied by policy")
+	// ErrApprovalRequired matches errors of commands requiring an approval when there is no approver
+	ErrApprovalRequired = errors.New("command requires approval")
+)
+
+// globs caches regular expressions of glob patterns
+var globs sync.Map
+
+type (
+	// Rule represents a policy rule, it matches an invocation if all the criteria it sets match. A pattern is a glob where
+	// * matches any text, including /; a word that is not literal (expansion, substitution, globbing) matches patterns of
+	// deny and approve rules but not of allow rules
+	Rule struct {
+		Name      string                 `json:"name,omitempty" yaml:"name,omitempty"`
+		Action    string                 `json:"action" yaml:"action"`
+		Reason    string                 `json:"reason,omitempty" yaml:"reason,omitempty"`
+		Commands  []string               `json:"commands,omitempty" yaml:"commands,omitempty"`   // executable names, or paths if the pattern has /
+		Args      []string               `json:"args,omitempty" yaml:"args,omitempty"`           // any argument matches a pattern
+		Redirects []string               `json:"redirects,omitempty" yaml:"redirects,omitempty"` // any redirection target matches a pattern
+		When      func(*Invocation) bool `json:"-" yaml:"-"`                                     // custom criteria
+	}
+
+	// Policy represents ordered rules evaluated on every invocation of a parsed command, the first matching rule decides an invocation
+	// and the most severe decision of all invocations decides the command
+	Policy struct {
+		Rules    []*Rule `json:"rules" yaml:"rules"`
+		Default  string  `json:"default,omitempty" yaml:"default,omitempty"`   // action of invocations no rule matches, Allow when empty
+		Subshell string  `json:"subshell,omitempty" yaml:"subshell,omitempty"` // action of invocations in subshells and nested scripts, Allow when empty
+		Dynamic  string  `json:"dynamic,omitempty" yaml:"dynamic,omitempty"`   // action of invocations whose name or script is not literal, Approve when empty
+	}
+
+	// Decision represents a policy decision
+	Decision struct {
+		Action     string
+		Reason     string
+		Rule       string // name of the deciding rule
+		Invocation string // invocation the decision was made on
+	}
+
+	// Approver decides a command requiring an approval, e.g. by asking an operator
+	Approver func(ctx context.Context, command string, decision *Decision) (bool, error)
+
+	// Error represents a command the policy did not allow to run
+	Error struct {
+		Command  string
+		Decision *Decision
+	}
+)
+
+// Error returns error message
+func (e *Error) Error() string {
+	message := fmt.Sprintf("command %q denied", e.Command)
+	if e.Decision.Action == Approve {
+		message = fmt.Sprintf("command %q requires approval", e.Command)
+	}
+	if e.Decision.Reason != "" {
+		message += ": " + e.Decision.Reason
+	}
+	return message
+}
+
+// Is returns true for ErrApprovalRequired if the command requires approval, otherwise for ErrDenied
+func (e *Error) Is(target error) bool {
+	if e.Decision.Action == Approve {
+		return target == ErrApprovalRequired
+	}
+	return target == ErrDenied
+}
+
+// severity orders actions, an unknown action is as severe as Deny
+func severity(action string) int {
+	switch action {
+	case Allow:
+		return 0
+	case Approve:
+		return 1
+	}
+	return 2
+}
+
+// Evaluate returns decision on the command, a command that can not be parsed is denied
+func (p *Policy) Evaluate(command string) *Decision {
+	invocations, err := Parse(command)
+	if err != nil {
+		return &Decision{Action: Deny, Reason: "failed to parse: " + err.Error()}
+	}
+	ret := &Decision{Action: Allow}
+	for _, invocation := range invocations {
+		if decision := p.evaluate(invocation); severity(decision.Action) > severity(ret.Action) {
+			ret = decision
+		}
+	}
+	return ret
+}
+
+func (p *Policy) evaluate(invocation *Invocation) *Decision {
+	ret := &Decision{Action: Allow, Invocation: invocation.String()}
+	if !invocation.Name.Literal {
+		ret.Action, ret.Reason = actionOr(p.Dynamic, Approve), "command is not literal: "+invocation.Name.Value
+		return ret
+	}
+	if invocation.Subshell && actionOr(p.Subshell, Allow) != Allow {
+		ret.Action, ret.Reason = p.Subshell, "runs in a subshell"
+	}
+	decision := &Decision{Action: actionOr(p.Default, Allow), Reason: "no rule matches", Invocation: ret.Invocation}
+	for _, rule := range p.Rules {
+		if rule.matches(invocation) {
+			decision = &Decision{Action: rule.Action, Reason: rule.Reason, Rule: rule.Name, Invocation: ret.Invocation}
+			break
+		}
+	}
+	if decision.Action == Allow {
+		decision.Reason = ""
+	}
+	if severity(decision.Action) >= severity(ret.Action) {
+		return decision
+	}
+	return ret
+}
+
+func actionOr(action, defaultAction string) string {
+	if action == "" {
+		return defaultAction
+	}
+	return action
+}
+
+func (r *Rule) matches(invocation *Invocation) bool {
+	conservative := r.Action != Allow
+	if len(r.Commands) > 0 && !matchesName(r.Commands, invocation.Name.Value) {
+		return false
+	}
+	if len(r.Args) > 0 && !matchesAny(r.Args, invocation.Args, conservative) {
+		return false
+	}
+	if len(r.Redirects) > 0 {
+		targets := make([]Word, len(invocation.Redirects))
+		for i, redirect := range invocation.Redirects {
+			targets[i] = redirect.Target
+		}
+		if !matchesAny(r.Redirects, targets, conservative) {
+			return false
+		}
+	}
+	return r.When == nil || r.When(invocation)
+}
+
+// matchesName returns true if the executable matches a pattern, patterns without / match its base name
+func matchesName(patterns []string, name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		candidate := name
+		if !strings.Contains(pattern, "/") {
+			candidate = path.Base(name)
+		}
+		if glob(pattern).MatchString(candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(patterns []string, words []Word, conservative bool) bool {
+	for _, word := range words {
+		if !word.Literal {
+			if conservative {
+				return true
+			}
+			continue
+		}
+		for _, pattern := range patterns {
+			if glob(pattern).MatchString(word.Value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// glob returns regular expression of a glob pattern
+func glob(pattern string) *regexp.Regexp {
+	if expr, ok := globs.Load(pattern); ok {
+		return expr.(*regexp.Regexp)
+	}
+	builder := strings.Builder{}
+	builder.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			builder.WriteString(".*")
+		case '?':
+			builder.WriteString(".")
+		case '[':
+			if end := strings.IndexByte(pattern[i+1:], ']'); end > 0 {
+				class := pattern[i+1 : i+1+end]
+				if class[0] == '!' {
+					class = "^" + class[1:]
+				}
+				builder.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+				i += end + 1
+				continue
+			}
+			builder.WriteString(`\[`)
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	builder.WriteString("$")
+	expr, err := regexp.Compile(builder.String())
+	if err != nil {
+		expr = regexp.MustCompile("^" + regexp.QuoteMeta(pattern) + "$")
+	}
+	globs.Store(pattern, expr)
+	return expr
+}
+
+// Middleware returns runner middleware enforcing the policy before the command is written to the shell. A command requiring
+// an approval runs if the approver approves it, without an approver it fails with ErrApprovalRequired.
+// Data sent with Send is not seen by middleware, see SendHook
+func (p *Policy) Middleware(approver Approver) runner.Middleware {
+	return func(next runner.RunFunc) runner.RunFunc {
+		return func(ctx context.Context, command string, options ...runner.Option) (*runner.Result, error) {
+			if err := p.enforce(ctx, command, approver); err != nil {
+				return nil, err
+			}
+			return next(ctx, command, options...)
+		}
+	}
+}
+
+// SendHook returns runner send hook enforcing the policy on data sent with Send that the shell reads as commands,
+// once the sent lines are a complete script, see runner.WrapSend
+func (p *Policy) SendHook(approver Approver) runner.SendHook {
+	return func(ctx context.Context, input string) error {
+		if incomplete(input) {
+			return runner.ErrIncomplete
+		}
+		return p.enforce(ctx, input, approver)
+	}
+}
+
+// enforce returns an error if the policy does not allow the command to run
+func (p *Policy) enforce(ctx context.Context, command string, approver Approver) error {
+	decision := p.Evaluate(command)
+	switch {
+	case decision.Action == Allow:
+	case decision.Action == Approve && approver != nil:
+		approved, err := approver(ctx, command, decision)
+		if err != nil {
+			return err
+		}
+		if !approved {
+			return &Error{Command: command, Decision: &Decision{Action: Deny, Reason: "not approved", Rule: decision.Rule, Invocation: decision.Invocation}}
+		}
+	default:
+		return &Error{Command: command, Decision: decision}
+	}
+	return nil
+}
+
+// Enforce returns runner running commands and reading sent data allowed by the policy, see Middleware and SendHook
+func Enforce(aRunner runner.Runner, policy *Policy, approver Approver) runner.Runner {
+	return runner.WrapSend(runner.Wrap(aRunner, policy.Middleware(approver)), policy.SendHook(approver))
+}
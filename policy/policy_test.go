@@ -0,0 +1,180 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/gosh/runner"
+)
+
+// fakeRunner records commands it runs and data sent to it
+type fakeRunner struct {
+	commands []string
+	sent     string
+	waiting  bool
+}
+
+func (f *fakeRunner) Run(ctx context.Context, command string, options ...runner.Option) (string, int, error) {
+	f.commands = append(f.commands, command)
+	return "ok", 0, nil
+}
+
+func (f *fakeRunner) Send(ctx context.Context, data []byte) (int, error) {
+	f.sent += string(data)
+	return len(data), nil
+}
+
+func (f *fakeRunner) Waiting() bool {
+	return f.waiting
+}
+
+func (f *fakeRunner) PID() int {
+	return 0
+}
+
+func (f *fakeRunner) Close() error {
+	return nil
+}
+
+func testPolicy() *Policy {
+	return &Policy{
+		Rules: []*Rule{
+			{Name: "rm", Action: Deny, Reason: "rm is not allowed", Commands: []string{"rm", "shred"}},
+			{Name: "etc", Action: Deny, Reason: "writes to /etc", Redirects: []string{"/etc/*"}},
+			{Name: "force push", Action: Approve, Reason: "force push", Commands: []string{"git"}, Args: []string{"--force", "-f"}},
+			{Name: "read only", Action: Allow, Commands: []string{"ls", "cat", "echo", "grep", "pwd", "cd", "git", "find", "sudo", "env", "sh", "bash"}},
+		},
+		Default: Approve,
+	}
+}
+
+func TestPolicy_Evaluate(t *testing.T) {
+	var testCases = []struct {
+		description string
+		policy      *Policy
+		command     string
+		expect      string
+		expectRule  string
+	}{
+		{description: "allowed", command: "ls -la /tmp | grep x", expect: Allow},
+		{description: "denied", command: "rm -rf /", expect: Deny, expectRule: "rm"},
+		{description: "denied path", command: "/bin/rm -rf /", expect: Deny, expectRule: "rm"},
+		{description: "escaped", command: `r\m -rf /`, expect: Deny, expectRule: "rm"},
+		{description: "quoted", command: `"r"'m' -rf /`, expect: Deny, expectRule: "rm"},
+		{description: "after allowed", command: "ls; rm -rf /", expect: Deny, expectRule: "rm"},
+		{description: "command substitution", command: "echo $(rm -rf /)", expect: Deny, expectRule: "rm"},
+		{description: "sudo", command: "sudo rm -rf /", expect: Deny, expectRule: "rm"},
+		{description: "env", command: "env -i A=1 shred x", expect: Deny, expectRule: "rm"},
+		{description: "nested script", command: `bash -c 'cd / && rm -rf .'`, expect: Deny, expectRule: "rm"},
+		{description: "find exec", command: `find . -exec rm {} +`, expect: Deny, expectRule: "rm"},
+		{description: "busybox", command: "busybox rm -rf /", expect: Deny, expectRule: "rm"},
+		{description: "watch", command: "watch rm x", expect: Deny, expectRule: "rm"},
+		{description: "flock", command: "flock /tmp/l rm x", expect: Deny, expectRule: "rm"},
+		{description: "unbuffer", command: "unbuffer rm x", expect: Deny, expectRule: "rm"},
+		{description: "parallel", command: "parallel rm ::: a", expect: Deny, expectRule: "rm"},
+		{description: "ssh", command: "ssh host rm x", expect: Deny, expectRule: "rm"},
+		{description: "redirect", command: "echo x > /etc/passwd", expect: Deny, expectRule: "etc"},
+		{description: "redirect of block", command: "{ echo x; } >> /etc/hosts", expect: Deny, expectRule: "etc"},
+		{description: "redirect only", command: "> /etc/passwd", expect: Deny, expectRule: "etc"},
+		{description: "dynamic redirect", command: "echo x > $TARGET", expect: Deny, expectRule: "etc"},
+		{description: "approval", command: "git push --force", expect: Approve, expectRule: "force push"},
+		{description: "dynamic arg", command: "git push $FLAGS", expect: Approve, expectRule: "force push"},
+		{description: "git allowed", command: "git status", expect: Allow},
+		{description: "default", command: "make build", expect: Approve},
+		{description: "dynamic command", command: "$CMD /", expect: Approve},
+		{description: "brace expansion", command: "{rm,-rf,/}", expect: Approve},
+		{description: "glob command", command: "/bin/r? -rf /", expect: Approve},
+		{description: "dynamic script", command: `sh -c "$SCRIPT"`, expect: Approve},
+		{description: "parse error", command: "ls (", expect: Deny},
+		{description: "subshell", policy: &Policy{Subshell: Deny}, command: "ls $(pwd)", expect: Deny},
+		{description: "subshell allowed", policy: &Policy{Subshell: Deny}, command: "ls /tmp", expect: Allow},
+		{description: "dynamic denied", policy: &Policy{Dynamic: Deny}, command: "$CMD", expect: Deny},
+		{description: "unknown action", policy: &Policy{Rules: []*Rule{{Action: "reject", Reason: "typo", Commands: []string{"*"}}}}, command: "ls", expect: "reject"},
+		{description: "when", policy: &Policy{Rules: []*Rule{{Action: Deny, Reason: "too many args", When: func(invocation *Invocation) bool { return len(invocation.Args) > 2 }}}}, command: "ls a b c", expect: Deny},
+	}
+	for _, testCase := range testCases {
+		policy := testCase.policy
+		if policy == nil {
+			policy = testPolicy()
+		}
+		decision := policy.Evaluate(testCase.command)
+		assert.Equal(t, testCase.expect, decision.Action, testCase.description)
+		assert.Equal(t, testCase.expectRule, decision.Rule, testCase.description)
+		if decision.Action != Allow {
+			assert.NotEmpty(t, decision.Reason, testCase.description)
+		}
+	}
+}
+
+func TestPolicy_Middleware(t *testing.T) {
+	ctx := context.Background()
+	approverErr := errors.New("approver unavailable")
+	var testCases = []struct {
+		description string
+		command     string
+		approver    Approver
+		expectErr   error
+		expectRun   bool
+	}{
+		{description: "allowed", command: "ls", expectRun: true},
+		{description: "denied", command: "rm -rf /", expectErr: ErrDenied},
+		{description: "no approver", command: "make", expectErr: ErrApprovalRequired},
+		{description: "approved", command: "make", approver: func(ctx context.Context, command string, decision *Decision) (bool, error) { return true, nil }, expectRun: true},
+		{description: "not approved", command: "make", approver: func(ctx context.Context, command string, decision *Decision) (bool, error) { return false, nil }, expectErr: ErrDenied},
+		{description: "approver error", command: "make", approver: func(ctx context.Context, command string, decision *Decision) (bool, error) { return false, approverErr }, expectErr: approverErr},
+		{description: "denied is not approved", command: "rm x", approver: func(ctx context.Context, command string, decision *Decision) (bool, error) { return true, nil }, expectErr: ErrDenied},
+	}
+	for _, testCase := range testCases {
+		fake := &fakeRunner{}
+		output, _, err := Enforce(fake, testPolicy(), testCase.approver).Run(ctx, testCase.command)
+		if testCase.expectErr != nil {
+			assert.ErrorIs(t, err, testCase.expectErr, testCase.description)
+		} else {
+			assert.Nil(t, err, testCase.description)
+			assert.Equal(t, "ok", output, testCase.description)
+		}
+		assert.Equal(t, testCase.expectRun, len(fake.commands) == 1, testCase.description)
+	}
+	_, _, err := Enforce(&fakeRunner{}, testPolicy(), nil).Run(ctx, "rm -rf /")
+	assert.EqualError(t, err, `command "rm -rf /" denied: rm is not allowed`)
+}
+
+func TestEnforce_Send(t *testing.T) {
+	ctx := context.Background()
+	var testCases = []struct {
+		description string
+		data        []string
+		waiting     bool
+		expectSent  string
+		expectErr   error
+	}{
+		{description: "allowed", data: []string{"ls /tmp\n"}, expectSent: "ls /tmp\n"},
+		{description: "denied", data: []string{"rm -rf /\n"}, expectErr: ErrDenied},
+		{description: "second line denied", data: []string{"ls\nrm -rf /\n"}, expectErr: ErrDenied},
+		{description: "split line denied", data: []string{"r", "m /tmp/x\n"}, expectErr: ErrDenied},
+		{description: "continued line denied", data: []string{"r\\\n", "m /tmp/x\n"}, expectErr: ErrDenied},
+		{description: "unclosed quote denied", data: []string{"echo 'a\n", "'; rm /tmp/x\n"}, expectErr: ErrDenied},
+		{description: "unclosed compound denied", data: []string{"if true; then\n", "rm /tmp/x; fi\n"}, expectErr: ErrDenied},
+		{description: "partial line held", data: []string{"ls\nr"}, expectSent: "ls\n"},
+		{description: "read by command", data: []string{"rm -rf /\n"}, waiting: true, expectSent: "rm -rf /\n"},
+	}
+	for _, testCase := range testCases {
+		fake := &fakeRunner{waiting: testCase.waiting}
+		enforced := Enforce(fake, testPolicy(), nil)
+		var err error
+		for _, data := range testCase.data {
+			if _, err = enforced.Send(ctx, []byte(data)); err != nil {
+				break
+			}
+		}
+		if testCase.expectErr != nil {
+			assert.ErrorIs(t, err, testCase.expectErr, testCase.description)
+			assert.Empty(t, fake.sent, testCase.description)
+			continue
+		}
+		assert.Nil(t, err, testCase.description)
+		assert.Equal(t, testCase.expectSent, fake.sent, testCase.description)
+	}
+}
@@ -1,16 +1,25 @@
 package runner
 
 import (
-	"fmt"
+	"errors"
+	"regexp"
 	"strings"
+	"time"
 )
 
 // Command represents a command
 type Command struct {
-	Stdin  string
-	Index  int
-	Stdout []string
-	Error  []string
+	Stdin      string        `json:"stdin"`
+	Index      int           `json:"index"`
+	Host       string        `json:"host,omitempty"`
+	Dir        string        `json:"dir,omitempty"` // working directory the command completed in
+	Stdout     []string      `json:"stdout,omitempty"`
+	Stderr     []string      `json:"stderr,omitempty"`
+	Error      []string      `json:"error,omitempty"`
+	ExitCode   int           `json:"exitCode"`
+	StartedAt  time.Time     `json:"startedAt"`
+	FinishedAt time.Time     `json:"finishedAt"`
+	Duration   time.Duration `json:"duration"`
 }
 
 // Output returns command output
@@ -23,7 +32,7 @@ func (c *Command) Err() error {
 	if len(c.Error) == 0 {
 		return nil
 	}
-	return fmt.Errorf(strings.Join(c.Error, "\n"))
+	return errors.New(strings.Join(c.Error, "\n"))
 }
 
 // NewCommand creates a new command
@@ -39,7 +48,49 @@ func NewCommand(command string, output string, err error) *Command {
 		ret.Stdout = strings.Split(output, "\n")
 	}
 	if errStr != "" {
-		ret.Stdout = strings.Split(errStr, "\n")
+		ret.Error = strings.Split(errStr, "\n")
 	}
 	return ret
 }
+
+// NewResultCommand creates a new command from a run result
+func NewResultCommand(command string, result *Result, err error) *Command {
+	ret := &Command{Stdin: command}
+	if result != nil {
+		if result.Stdout != "" {
+			ret.Stdout = strings.Split(result.Stdout, "\n")
+		}
+		if result.Stderr != "" {
+			ret.Stderr = strings.Split(result.Stderr, "\n")
+		}
+		ret.ExitCode = result.ExitCode
+		ret.StartedAt = result.StartedAt
+		ret.Duration = result.Duration
+		ret.FinishedAt = result.StartedAt.Add(result.Duration)
+	}
+	if err != nil {
+		ret.Error = strings.Split(err.Error(), "\n")
+	}
+	return ret
+}
+
+// size returns number of command text bytes
+func (c *Command) size() int {
+	ret := len(c.Stdin) + len(c.Host) + len(c.Dir)
+	for _, lines := range [][]string{c.Stdout, c.Stderr, c.Error} {
+		for _, line := range lines {
+			ret += len(line) + 1
+		}
+	}
+	return ret
+}
+
+// redact masks text matching any of the expressions in the command, its output and error
+func (c *Command) redact(expressions []*regexp.Regexp) {
+	c.Stdin = redact(c.Stdin, expressions)
+	for _, lines := range [][]string{c.Stdout, c.Stderr, c.Error} {
+		for i, line := range lines {
+			lines[i] = redact(line, expressions)
+		}
+	}
+}
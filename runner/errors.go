@@ -0,0 +1,138 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrTimeout matches errors of commands that did not complete within the timeout
+	ErrTimeout = errors.New("command timed out")
+	// ErrExit matches errors of commands that exited with non zero code
+	ErrExit = errors.New("command failed")
+	// ErrConnection matches errors of broken or failed connections to the shell
+	ErrConnection = errors.New("connection failed")
+	// ErrNotFound matches errors of commands or interactions that were not found
+	ErrNotFound = errors.New("not found")
+)
+
+// LocalHost represents host name reported by errors of local commands
+const LocalHost = "localhost"
+
+type (
+	// ExitError represents a command that exited with non zero code, see WithExitError
+	ExitError struct {
+		Command  string
+		Host     string
+		ExitCode int
+		Stdout   string
+		Stderr   string
+	}
+
+	// TimeoutError represents a command that did not complete within the timeout or the context deadline,
+	// the command was interrupted, output read until then is kept
+	TimeoutError struct {
+		Command string
+		Host    string
+		Timeout time.Duration
+		Stream  Stream // stream read last
+		Stdout  string
+		Stderr  string
+		Cause   error
+	}
+
+	// ConnectionError represents broken or failed connection to the shell
+	ConnectionError struct {
+		Command string
+		Host    string
+		Stream  Stream // stream that failed, empty when connecting
+		Stdout  string
+		Stderr  string
+		Cause   error
+	}
+)
+
+// Error returns error message
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command %q failed on %v with exit code %v", e.Command, e.Host, e.ExitCode)
+}
+
+// Is returns true for ErrExit
+func (e *ExitError) Is(target error) bool {
+	return target == ErrExit
+}
+
+// Error returns error message
+func (e *TimeoutError) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("command %q timed out on %v: %v", e.Command, e.Host, e.Cause)
+	}
+	return fmt.Sprintf("command %q timed out on %v after %v", e.Command, e.Host, e.Timeout)
+}
+
+// Is returns true for ErrTimeout
+func (e *TimeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+// Unwrap returns cause
+func (e *TimeoutError) Unwrap() error {
+	return e.Cause
+}
+
+// Error returns error message
+func (e *ConnectionError) Error() string {
+	message := fmt.Sprintf("connection to %v failed", e.Host)
+	switch e.Stream {
+	case "":
+	case Stdin:
+		message += " writing " + string(e.Stream)
+	default:
+		message += " reading " + string(e.Stream)
+	}
+	if e.Command != "" {
+		message += fmt.Sprintf(" while running %q", e.Command)
+	}
+	if e.Cause != nil {
+		message += ": " + e.Cause.Error()
+	}
+	return message
+}
+
+// Is returns true for ErrConnection
+func (e *ConnectionError) Is(target error) bool {
+	return target == ErrConnection
+}
+
+// Unwrap returns cause
+func (e *ConnectionError) Unwrap() error {
+	return e.Cause
+}
+
+// Complete sets command and host on typed errors, returns PrivilegeError for a failed privilege escalation and,
+// when the options request it with WithExitError, ExitError for a non zero exit code
+func (o *Options) Complete(command, host string, result *Result, err error) error {
+	var timeoutErr *TimeoutError
+	var connectionErr *ConnectionError
+	var reason error
+	if err == nil && o.privilege != nil && result != nil && result.ExitCode != 0 {
+		reason = o.privilege.failure(result)
+	}
+	switch {
+	case errors.As(err, &timeoutErr):
+		timeoutErr.Command, timeoutErr.Host = command, host
+	case errors.As(err, &connectionErr):
+		if connectionErr.Command == "" {
+			connectionErr.Command = command
+		}
+		if connectionErr.Host == "" {
+			connectionErr.Host = host
+		}
+	case reason != nil:
+		return &PrivilegeError{Command: command, Host: host, Strategy: o.privilege.strategy(), User: o.privilege.user(), Reason: reason, Stderr: result.Stderr}
+	case err == nil && o.exitError && result != nil && result.ExitCode != 0:
+		return &ExitError{Command: command, Host: host, ExitCode: result.ExitCode, Stdout: result.Stdout, Stderr: result.Stderr}
+	}
+	return err
+}
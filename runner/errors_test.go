@@ -0,0 +1,76 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy/cred"
+)
+
+func TestOptions_Complete(t *testing.T) {
+	var testCases = []struct {
+		description string
+		options     []Option
+		result      *Result
+		err         error
+		expect      error
+		is          []error
+	}{
+		{description: "success", result: &Result{}},
+		{description: "non zero exit code", result: &Result{ExitCode: 2}},
+		{
+			description: "exit error",
+			options:     []Option{WithExitError()},
+			result:      &Result{ExitCode: 2, Stdout: "out", Stderr: "err"},
+			expect:      &ExitError{Command: "make", Host: "host1", ExitCode: 2, Stdout: "out", Stderr: "err"},
+			is:          []error{ErrExit},
+		},
+		{
+			description: "timeout",
+			result:      &Result{ExitCode: -1},
+			err:         &TimeoutError{Stream: Stderr, Stdout: "partial", Cause: context.DeadlineExceeded},
+			expect:      &TimeoutError{Command: "make", Host: "host1", Stream: Stderr, Stdout: "partial", Cause: context.DeadlineExceeded},
+			is:          []error{ErrTimeout, context.DeadlineExceeded},
+		},
+		{
+			description: "connection",
+			options:     []Option{WithExitError()},
+			result:      &Result{ExitCode: -1},
+			err:         &ConnectionError{Stream: Stdout, Cause: io.EOF},
+			expect:      &ConnectionError{Command: "make", Host: "host1", Stream: Stdout, Cause: io.EOF},
+			is:          []error{ErrConnection, io.EOF},
+		},
+		{
+			description: "sudo password required",
+			options:     []Option{WithSudo(""), WithExitError()},
+			result:      &Result{ExitCode: 1, Stderr: "sudo: a password is required\n"},
+			expect:      &PrivilegeError{Command: "make", Host: "host1", Strategy: EscalationSudo, User: "root", Reason: ErrPasswordRequired, Stderr: "sudo: a password is required\n"},
+			is:          []error{ErrPasswordRequired},
+		},
+		{
+			description: "sudoers",
+			options:     []Option{WithPrivilege(&Privilege{Credential: &cred.Basic{Password: "secret"}}), WithSudo("postgres")},
+			result:      &Result{ExitCode: 1, Stderr: "sudo: dev is not in the sudoers file.\n"},
+			expect:      &PrivilegeError{Command: "make", Host: "host1", Strategy: EscalationSudo, User: "postgres", Reason: ErrNotPermitted, Stderr: "sudo: dev is not in the sudoers file.\n"},
+			is:          []error{ErrNotPermitted},
+		},
+		{
+			description: "su without password",
+			options:     []Option{WithPrivilege(&Privilege{Strategy: EscalationSu})},
+			result:      &Result{ExitCode: 1, Stdout: "Password: \nsu: Authentication failure\n"},
+			expect:      &PrivilegeError{Command: "make", Host: "host1", Strategy: EscalationSu, User: "root", Reason: ErrPasswordRequired},
+			is:          []error{ErrPasswordRequired},
+		},
+		{description: "command failure with sudo", options: []Option{WithSudo("")}, result: &Result{ExitCode: 2, Stderr: "make: no target\n"}},
+	}
+	for _, testCase := range testCases {
+		actual := NewOptions(testCase.options).Complete("make", "host1", testCase.result, testCase.err)
+		assert.Equal(t, testCase.expect, actual, testCase.description)
+		for _, target := range testCase.is {
+			assert.True(t, errors.Is(actual, target), testCase.description)
+		}
+	}
+}
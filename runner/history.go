@@ -1,11 +1,257 @@
 package runner
 
-// History represents command history
-type History struct {
-	Commands []*Command
+import (
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	// History represents command history safe for concurrent use. Commands are appended once completed and saved to
+	// the history store if one is set; memory is unbounded unless a capacity or byte budget is set, then the oldest
+	// commands are evicted once it is exceeded
+	History struct {
+		// Commands are commands kept in memory, oldest first.
+		//
+		// Deprecated: the field is not safe for concurrent use and commands appended to it are neither indexed nor saved,
+		// use Entries, Query and Append instead
+		Commands []*Command
+		mux      sync.RWMutex
+		size     int
+		capacity int
+		budget   int
+		store    HistoryStore
+		redacted []*regexp.Regexp
+		next     int
+		err      error
+	}
+
+	// HistoryOption represents a history option
+	HistoryOption func(h *History)
+
+	// HistoryQuery represents history query criteria, zero values match any command
+	HistoryQuery struct {
+		ExitCode *int           // exit code
+		Command  *regexp.Regexp // matches command text
+		Output   *regexp.Regexp // matches any line of stdout, stderr or error
+		Since    time.Time      // started at or after
+		Until    time.Time      // started before
+		From     int            // lowest command index, the Next of the previous page
+		Limit    int            // maximum number of commands
+		Last     int            // most recent matching commands, From and Limit are ignored
+	}
+
+	// HistoryPage represents commands matching a query, oldest first
+	HistoryPage struct {
+		Commands []*Command
+		Next     int  // From of the next page
+		More     bool // commands past Next are yet to be queried
+	}
+
+	// HistoryRunner represents a runner recording command history
+	HistoryRunner interface {
+		// History returns command history, nil if not recorded
+		History() *History
+	}
+)
+
+// WithHistoryStore creates with history store option, completed commands are appended to the store
+func WithHistoryStore(store HistoryStore) HistoryOption {
+	return func(h *History) {
+		h.store = store
+	}
+}
+
+// WithHistoryCapacity creates with history capacity option, zero or less keeps any number of commands
+func WithHistoryCapacity(capacity int) HistoryOption {
+	return func(h *History) {
+		h.capacity = capacity
+	}
+}
+
+// WithHistoryBudget creates with history byte budget option limiting command text kept in memory, zero or less is unlimited;
+// the last command is kept even if it exceeds the budget
+func WithHistoryBudget(budget int) HistoryOption {
+	return func(h *History) {
+		h.budget = budget
+	}
+}
+
+// WithHistoryRedact creates with history redact option, text matching any of the expressions is masked in the command,
+// its output and error before the command is kept or saved
+func WithHistoryRedact(expressions ...*regexp.Regexp) HistoryOption {
+	return func(h *History) {
+		h.redacted = append(h.redacted, expressions...)
+	}
+}
+
+// Append appends a completed command and saves it to the store, the command index is set to its sequence number in the history
+func (h *History) Append(command *Command) error {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if len(h.redacted) > 0 {
+		command.redact(h.redacted)
+	}
+	command.Index = h.next
+	h.next++
+	h.push(command)
+	if h.store == nil {
+		return nil
+	}
+	err := h.store.Append(command)
+	if err != nil && h.err == nil {
+		h.err = err
+	}
+	return err
+}
+
+func (h *History) push(command *Command) {
+	if h.capacity > 0 && len(h.Commands) >= h.capacity {
+		h.evict()
+	}
+	h.Commands = append(h.Commands, command)
+	h.size += command.size()
+	for h.budget > 0 && h.size > h.budget && len(h.Commands) > 1 {
+		h.evict()
+	}
+}
+
+// evict removes the oldest command, append reallocates the slice without evicted commands once it is full
+func (h *History) evict() {
+	h.size = max(h.size-h.Commands[0].size(), 0)
+	h.Commands[0] = nil
+	h.Commands = h.Commands[1:]
+}
+
+// at returns i-th command kept in memory, oldest first
+func (h *History) at(i int) *Command {
+	return h.Commands[i]
+}
+
+// Err returns the first error of saving a command to the store
+func (h *History) Err() error {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+	return h.err
+}
+
+// Len returns number of commands kept in memory
+func (h *History) Len() int {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+	return len(h.Commands)
+}
+
+// Size returns number of command text bytes kept in memory
+func (h *History) Size() int {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+	return h.size
+}
+
+// Entries returns a snapshot of commands kept in memory, oldest first; the commands must not be modified
+func (h *History) Entries() []*Command {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+	return append([]*Command{}, h.Commands...)
+}
+
+// Last returns up to n most recent commands, oldest first
+func (h *History) Last(n int) []*Command {
+	return h.Query(&HistoryQuery{Last: n}).Commands
+}
+
+// Query returns commands matching the query, oldest first. A page is located by its From index without scanning earlier commands
+func (h *History) Query(query *HistoryQuery) *HistoryPage {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+	ret := &HistoryPage{Commands: []*Command{}}
+	if query.Last > 0 {
+		for i := len(h.Commands) - 1; i >= 0 && len(ret.Commands) < query.Last; i-- {
+			if command := h.at(i); query.matches(command) {
+				ret.Commands = append(ret.Commands, command)
+			}
+		}
+		for i, j := 0, len(ret.Commands)-1; i < j; i, j = i+1, j-1 {
+			ret.Commands[i], ret.Commands[j] = ret.Commands[j], ret.Commands[i]
+		}
+		ret.Next = h.next
+		return ret
+	}
+	ret.Next = max(query.From, 0)
+	i := sort.Search(len(h.Commands), func(i int) bool { return h.at(i).Index >= query.From })
+	for ; i < len(h.Commands); i++ {
+		if query.Limit > 0 && len(ret.Commands) == query.Limit {
+			ret.More = true
+			break
+		}
+		command := h.at(i)
+		if query.matches(command) {
+			ret.Commands = append(ret.Commands, command)
+		}
+		ret.Next = command.Index + 1
+	}
+	return ret
+}
+
+func (q *HistoryQuery) matches(command *Command) bool {
+	switch {
+	case q.ExitCode != nil && command.ExitCode != *q.ExitCode:
+		return false
+	case !q.Since.IsZero() && command.StartedAt.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && !command.StartedAt.Before(q.Until):
+		return false
+	case q.Command != nil && !q.Command.MatchString(command.Stdin):
+		return false
+	case q.Output != nil && !matchesAny(q.Output, command.Stdout, command.Stderr, command.Error):
+		return false
+	}
+	return true
+}
+
+func matchesAny(expr *regexp.Regexp, outputs ...[]string) bool {
+	for _, lines := range outputs {
+		for _, line := range lines {
+			if expr.MatchString(line) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NewHistory creates a command history, memory is unbounded unless WithHistoryCapacity or WithHistoryBudget is set
+func NewHistory(options ...HistoryOption) *History {
+	ret := &History{Commands: make([]*Command, 0)}
+	for _, option := range options {
+		option(ret)
+	}
+	return ret
+}
+
+// LoadHistory creates a command history with commands loaded from the store, e.g. to audit a session after a crash;
+// the loaded commands are not saved again, commands appended later are saved to the store
+func LoadHistory(store HistoryStore, options ...HistoryOption) (*History, error) {
+	commands, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	ret := NewHistory(append([]HistoryOption{WithHistoryStore(store)}, options...)...)
+	for _, command := range commands {
+		ret.push(command)
+	}
+	if count := len(commands); count > 0 {
+		ret.next = commands[count-1].Index + 1
+	}
+	return ret, nil
 }
 
-// NewHistory creates a command history
-func NewHistory() *History {
-	return &History{Commands: make([]*Command, 0)}
+// HistoryOf returns command history of runners implementing HistoryRunner, otherwise nil
+func HistoryOf(runner Runner) *History {
+	if historyRunner, ok := runner.(HistoryRunner); ok {
+		return historyRunner.History()
+	}
+	return nil
 }
@@ -0,0 +1,205 @@
+package runner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCommand(t *testing.T) {
+	command := NewCommand("ls /tmp", "a\nb", errors.New("failed"))
+	assert.Equal(t, []string{"a", "b"}, command.Stdout)
+	assert.Equal(t, []string{"failed"}, command.Error)
+	assert.EqualError(t, command.Err(), "failed")
+}
+
+func TestHistory_Append(t *testing.T) {
+	startedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	var testCases = []struct {
+		description string
+		store       func(t *testing.T) HistoryStore
+	}{
+		{description: "memory", store: func(t *testing.T) HistoryStore { return NewMemoryStore() }},
+		{description: "file", store: func(t *testing.T) HistoryStore { return NewFileStore(filepath.Join(t.TempDir(), "history.jsonl")) }},
+	}
+	for _, testCase := range testCases {
+		store := testCase.store(t)
+		history := NewHistory(WithHistoryStore(store))
+		first := NewResultCommand("cd /tmp && ls", &Result{Stdout: "a\nb", ExitCode: 0, StartedAt: startedAt, Duration: time.Second}, nil)
+		first.Host, first.Dir = "web1", "/tmp"
+		second := NewResultCommand("false", &Result{ExitCode: 1, StartedAt: startedAt.Add(time.Minute), Duration: time.Millisecond}, errors.New("exit code 1"))
+		assert.Nil(t, history.Append(first), testCase.description)
+		assert.Nil(t, history.Append(second), testCase.description)
+		assert.Nil(t, history.Err(), testCase.description)
+
+		loaded, err := LoadHistory(store)
+		if !assert.Nil(t, err, testCase.description) || !assert.Len(t, loaded.Entries(), 2, testCase.description) {
+			continue
+		}
+		assert.EqualValues(t, first, loaded.Entries()[0], testCase.description)
+		assert.Equal(t, "web1", loaded.Entries()[0].Host, testCase.description)
+		assert.Equal(t, startedAt.Add(time.Second), loaded.Entries()[0].FinishedAt.UTC(), testCase.description)
+		assert.Equal(t, 1, loaded.Entries()[1].ExitCode, testCase.description)
+		assert.EqualError(t, loaded.Entries()[1].Err(), "exit code 1", testCase.description)
+
+		third := NewCommand("pwd", "/tmp", nil)
+		assert.Nil(t, loaded.Append(third), testCase.description)
+		assert.Equal(t, 2, third.Index, testCase.description)
+		commands, err := store.Load()
+		assert.Nil(t, err, testCase.description)
+		assert.Len(t, commands, 3, testCase.description)
+	}
+}
+
+func TestHistory_Redact(t *testing.T) {
+	store := NewMemoryStore()
+	history := NewHistory(WithHistoryStore(store), WithHistoryRedact(regexp.MustCompile(`token=\S+`)))
+	command := NewResultCommand("login token=abc", &Result{Stdout: "ok\ntoken=abc", Stderr: "token=abc"}, errors.New("failed with token=abc"))
+	assert.Nil(t, history.Append(command))
+	stored, err := store.Load()
+	if assert.Nil(t, err) && assert.Len(t, stored, 1) {
+		assert.Equal(t, "login ******", stored[0].Stdin)
+		assert.Equal(t, []string{"ok", "******"}, stored[0].Stdout)
+		assert.Equal(t, []string{"******"}, stored[0].Stderr)
+		assert.Equal(t, []string{"failed with ******"}, stored[0].Error)
+	}
+}
+
+func TestFileStore_Load(t *testing.T) {
+	var testCases = []struct {
+		description string
+		content     *string
+		expect      []string
+		expectErr   bool
+	}{
+		{description: "missing file"},
+		{description: "empty file", content: ptr("")},
+		{description: "commands", content: ptr("{\"stdin\":\"ls\"}\n\n{\"stdin\":\"pwd\",\"index\":1}\n"), expect: []string{"ls", "pwd"}},
+		{description: "incomplete last line", content: ptr("{\"stdin\":\"ls\"}\n{\"stdin\":\"pw"), expect: []string{"ls"}},
+		{description: "corrupted line", content: ptr("{\"stdin\":\"ls\"}\nnot json\n{\"stdin\":\"pwd\"}\n"), expectErr: true},
+	}
+	for _, testCase := range testCases {
+		location := filepath.Join(t.TempDir(), "history.jsonl")
+		if testCase.content != nil {
+			assert.Nil(t, os.WriteFile(location, []byte(*testCase.content), 0600), testCase.description)
+		}
+		commands, err := NewFileStore(location).Load()
+		if testCase.expectErr {
+			assert.NotNil(t, err, testCase.description)
+			continue
+		}
+		assert.Nil(t, err, testCase.description)
+		var actual []string
+		for _, command := range commands {
+			actual = append(actual, command.Stdin)
+		}
+		assert.Equal(t, testCase.expect, actual, testCase.description)
+	}
+}
+
+func ptr(text string) *string {
+	return &text
+}
+
+func TestHistory_Bounds(t *testing.T) {
+	var testCases = []struct {
+		description string
+		options     []HistoryOption
+		commands    []string
+		expect      []string
+		expectSize  int
+	}{
+		{description: "unbounded by default", commands: []string{"a", "b", "c"}, expect: []string{"a", "b", "c"}, expectSize: 3},
+		{description: "unbounded", options: []HistoryOption{WithHistoryCapacity(0), WithHistoryBudget(0)}, commands: []string{"a", "b", "c"}, expect: []string{"a", "b", "c"}, expectSize: 3},
+		{description: "capacity", options: []HistoryOption{WithHistoryCapacity(2)}, commands: []string{"a", "b", "c"}, expect: []string{"b", "c"}, expectSize: 2},
+		{description: "budget", options: []HistoryOption{WithHistoryBudget(5)}, commands: []string{"aa", "bb", "cc"}, expect: []string{"bb", "cc"}, expectSize: 4},
+		{description: "last kept over budget", options: []HistoryOption{WithHistoryBudget(2)}, commands: []string{"a", "long"}, expect: []string{"long"}, expectSize: 4},
+	}
+	for _, testCase := range testCases {
+		history := NewHistory(testCase.options...)
+		for _, command := range testCase.commands {
+			assert.Nil(t, history.Append(&Command{Stdin: command}), testCase.description)
+		}
+		var actual []string
+		for _, command := range history.Entries() {
+			actual = append(actual, command.Stdin)
+		}
+		assert.Equal(t, testCase.expect, actual, testCase.description)
+		assert.Equal(t, history.Entries(), history.Commands, testCase.description) // deprecated field
+		assert.Equal(t, len(testCase.expect), history.Len(), testCase.description)
+		assert.Equal(t, testCase.expectSize, history.Size(), testCase.description)
+	}
+}
+
+func TestHistory_Concurrent(t *testing.T) {
+	history := NewHistory(WithHistoryCapacity(100))
+	done := make(chan bool)
+	for i := 0; i < 4; i++ {
+		go func() {
+			for j := 0; j < 250; j++ {
+				_ = history.Append(&Command{Stdin: "echo"})
+				history.Last(10)
+			}
+			done <- true
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+	commands := history.Entries()
+	assert.Len(t, commands, 100)
+	for i, command := range commands {
+		assert.Equal(t, 900+i, command.Index)
+	}
+}
+
+func TestHistory_Query(t *testing.T) {
+	startedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	history := NewHistory(WithHistoryCapacity(5))
+	for i, command := range []*Command{
+		{Stdin: "evicted"},
+		{Stdin: "ls /tmp", Stdout: []string{"a.txt", "b.log"}},
+		{Stdin: "cat b.log", Stderr: []string{"permission denied"}, ExitCode: 1},
+		{Stdin: "ls /var", Stdout: []string{"log"}},
+		{Stdin: "make", Error: []string{"exit code 2"}, ExitCode: 2},
+		{Stdin: "ls /", Stdout: []string{"tmp", "var"}},
+	} {
+		command.StartedAt = startedAt.Add(time.Duration(i) * time.Minute)
+		assert.Nil(t, history.Append(command))
+	}
+	failed := 1
+	var testCases = []struct {
+		description string
+		query       *HistoryQuery
+		expect      []int
+		expectNext  int
+		expectMore  bool
+	}{
+		{description: "all", query: &HistoryQuery{}, expect: []int{1, 2, 3, 4, 5}, expectNext: 6},
+		{description: "last", query: &HistoryQuery{Last: 2}, expect: []int{4, 5}, expectNext: 6},
+		{description: "last matching", query: &HistoryQuery{Last: 2, Command: regexp.MustCompile(`^ls`)}, expect: []int{3, 5}, expectNext: 6},
+		{description: "exit code", query: &HistoryQuery{ExitCode: &failed}, expect: []int{2}, expectNext: 6},
+		{description: "output", query: &HistoryQuery{Output: regexp.MustCompile(`log|exit code`)}, expect: []int{1, 3, 4}, expectNext: 6},
+		{description: "time range", query: &HistoryQuery{Since: startedAt.Add(2 * time.Minute), Until: startedAt.Add(4 * time.Minute)}, expect: []int{2, 3}, expectNext: 6},
+		{description: "first page", query: &HistoryQuery{Limit: 2}, expect: []int{1, 2}, expectNext: 3, expectMore: true},
+		{description: "next page", query: &HistoryQuery{From: 3, Limit: 2}, expect: []int{3, 4}, expectNext: 5, expectMore: true},
+		{description: "last page", query: &HistoryQuery{From: 5, Limit: 2}, expect: []int{5}, expectNext: 6},
+		{description: "filtered page", query: &HistoryQuery{Command: regexp.MustCompile(`^ls`), Limit: 2}, expect: []int{1, 3}, expectNext: 4, expectMore: true},
+		{description: "past end", query: &HistoryQuery{From: 10}, expect: nil, expectNext: 10},
+	}
+	for _, testCase := range testCases {
+		page := history.Query(testCase.query)
+		var actual []int
+		for _, command := range page.Commands {
+			actual = append(actual, command.Index)
+		}
+		assert.Equal(t, testCase.expect, actual, testCase.description)
+		assert.Equal(t, testCase.expectNext, page.Next, testCase.description)
+		assert.Equal(t, testCase.expectMore, page.More, testCase.description)
+	}
+}
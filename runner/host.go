@@ -0,0 +1,15 @@
+package runner
+
+// HostRunner represents a runner reporting the host its commands run on
+type HostRunner interface {
+	//Host returns host name reported by errors of the runner commands
+	Host() string
+}
+
+// HostOf returns host of a runner implementing HostRunner, empty otherwise
+func HostOf(runner Runner) string {
+	if hostRunner, ok := runner.(HostRunner); ok {
+		return hostRunner.Host()
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// ErrIncomplete is returned by a SendHook for input that is not a complete script yet, e.g. with an unclosed quote
+var ErrIncomplete = errors.New("incomplete input")
+
+type (
+	// InputRunner represents a runner reporting whether a running command reads data sent with Send
+	InputRunner interface {
+		//Waiting returns true if a command run with WithInteractive is running and reads the shell stdin,
+		//otherwise data sent with Send is read by the shell as commands
+		Waiting() bool
+	}
+
+	// SendHook checks complete lines sent with Send that the shell reads as commands before they are written, see WrapSend;
+	// it returns ErrIncomplete to hold the input until more is sent, or an error to reject it
+	SendHook func(ctx context.Context, input string) error
+)
+
+// Waiting returns true if a runner implementing InputRunner has a command reading the shell stdin
+func Waiting(runner Runner) bool {
+	inputRunner, ok := runner.(InputRunner)
+	return ok && inputRunner.Waiting()
+}
+
+// WrapSend returns a runner checking data sent with Send with the hooks, e.g. a command policy; middleware of a wrapped
+// runner is kept. Data read by a running command (see InputRunner) is passed through, otherwise the shell reads it as commands:
+// it is held until its lines are complete and written once every hook accepts them, rejected input is dropped
+func WrapSend(runner Runner, hooks ...SendHook) Runner {
+	if len(hooks) == 0 {
+		return runner
+	}
+	if aWrapper, ok := runner.(*wrapper); ok {
+		return &wrapper{runner: aWrapper.runner, middlewares: aWrapper.middlewares, run: aWrapper.run, hooks: append(slices.Clone(aWrapper.hooks), hooks...)}
+	}
+	return &wrapper{runner: runner, run: func(ctx context.Context, command string, options ...Option) (*Result, error) {
+		return RunResult(ctx, runner, command, options...)
+	}, hooks: hooks}
+}
@@ -0,0 +1,175 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSignalNotSupported is returned when a job can not be signalled
+var ErrSignalNotSupported = errors.New("signal not supported")
+
+type (
+	// Job represents a command running in the background
+	Job struct {
+		ID        string
+		Command   string
+		StartedAt time.Time
+		mux       sync.Mutex
+		stdout    strings.Builder
+		stderr    strings.Builder
+		stdoutPos int
+		stderrPos int
+		done      chan struct{}
+		result    *Result
+		err       error
+		signal    func(sig os.Signal) error
+	}
+
+	// JobRunner represents a runner that starts background jobs without blocking its session
+	JobRunner interface {
+		//Start starts supplied command in the background
+		Start(ctx context.Context, command string, options ...Option) (*Job, error)
+	}
+
+	jobWriter struct {
+		job    *Job
+		stream Stream
+	}
+)
+
+// Write appends data to the job output
+func (w *jobWriter) Write(data []byte) (int, error) {
+	w.job.mux.Lock()
+	defer w.job.mux.Unlock()
+	if w.stream == Stderr {
+		return w.job.stderr.Write(data)
+	}
+	return w.job.stdout.Write(data)
+}
+
+// Writer returns writer capturing supplied job output stream
+func (j *Job) Writer(stream Stream) io.Writer {
+	return &jobWriter{job: j, stream: stream}
+}
+
+// Output returns stdout and stderr written since the previous call
+func (j *Job) Output() (stdout, stderr string) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	stdout = j.stdout.String()[j.stdoutPos:]
+	stderr = j.stderr.String()[j.stderrPos:]
+	j.stdoutPos = j.stdout.Len()
+	j.stderrPos = j.stderr.Len()
+	return stdout, stderr
+}
+
+// Done returns a channel closed when the job has finished
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// ExitCode returns job exit code, ok is false while the job is running
+func (j *Job) ExitCode() (code int, ok bool) {
+	select {
+	case <-j.done:
+		return j.result.ExitCode, true
+	default:
+		return 0, false
+	}
+}
+
+// Wait waits for the job to finish and returns its whole output
+func (j *Job) Wait(ctx context.Context) (*Result, error) {
+	select {
+	case <-j.done:
+		return j.result, j.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Signal sends signal to the job
+func (j *Job) Signal(sig os.Signal) error {
+	select {
+	case <-j.done:
+		return nil
+	default:
+	}
+	if j.signal == nil {
+		return ErrSignalNotSupported
+	}
+	return j.signal(sig)
+}
+
+// Kill kills the job
+func (j *Job) Kill() error {
+	return j.Signal(os.Kill)
+}
+
+// Finish marks the job as finished with supplied exit code and error
+func (j *Job) Finish(exitCode int, err error) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	select {
+	case <-j.done:
+		return
+	default:
+	}
+	j.result = &Result{
+		Stdout:    j.stdout.String(),
+		Stderr:    j.stderr.String(),
+		ExitCode:  exitCode,
+		StartedAt: j.StartedAt,
+		Duration:  time.Since(j.StartedAt),
+	}
+	j.err = err
+	close(j.done)
+}
+
+// NewJob creates a job, signal delivers signals to the running command
+func NewJob(command string, signal func(sig os.Signal) error) *Job {
+	return &Job{
+		ID:        newCommandID(),
+		Command:   command,
+		StartedAt: time.Now(),
+		done:      make(chan struct{}),
+		signal:    signal,
+	}
+}
+
+// Start starts supplied command in the background; runners that do not implement JobRunner run it on their session,
+// which is then busy until the job finishes and the job can not be signalled
+func Start(ctx context.Context, runner Runner, command string, options ...Option) (*Job, error) {
+	if jobRunner, ok := runner.(JobRunner); ok {
+		return jobRunner.Start(ctx, command, options...)
+	}
+	return startOnSession(ctx, runner, command, options...)
+}
+
+// startOnSession runs supplied command on the runner session in the background
+func startOnSession(ctx context.Context, runner Runner, command string, options ...Option) (*Job, error) {
+	job := NewJob(command, nil)
+	go func() {
+		options = append(options, WithChunkListener(func(stream Stream, chunk string) {
+			_, _ = job.Writer(stream).Write([]byte(chunk))
+		}))
+		result, err := RunResult(ctx, runner, command, options...)
+		if result == nil {
+			job.Finish(-1, err)
+			return
+		}
+		job.mux.Lock()
+		if job.stdout.Len() == 0 && job.stderr.Len() == 0 {
+			job.stdout.WriteString(result.Stdout)
+			job.stderr.WriteString(result.Stderr)
+		}
+		job.mux.Unlock()
+		job.Finish(result.ExitCode, err)
+	}()
+	return job, nil
+}
@@ -0,0 +1,34 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJob(t *testing.T) {
+	job := NewJob("make", nil)
+	_, _ = job.Writer(Stdout).Write([]byte("a"))
+	_, _ = job.Writer(Stderr).Write([]byte("b"))
+	stdout, stderr := job.Output()
+	assert.Equal(t, "a", stdout)
+	assert.Equal(t, "b", stderr)
+	_, _ = job.Writer(Stdout).Write([]byte("c"))
+	stdout, stderr = job.Output()
+	assert.Equal(t, "c", stdout)
+	assert.Equal(t, "", stderr)
+	assert.Equal(t, ErrSignalNotSupported, job.Signal(os.Interrupt))
+	_, ok := job.ExitCode()
+	assert.False(t, ok)
+
+	job.Finish(2, nil)
+	code, ok := job.ExitCode()
+	assert.True(t, ok)
+	assert.Equal(t, 2, code)
+	result, err := job.Wait(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "ac", result.Stdout)
+	assert.Nil(t, job.Kill())
+}
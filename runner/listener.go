@@ -2,3 +2,19 @@ package runner
 
 // Listener represent command listener (it will send stdout fragments as thier being available on stdout)
 type Listener func(stdout string, hasMore bool)
+
+// Stream represents an output stream
+type Stream string
+
+const (
+	// Stdout represents standard output
+	Stdout Stream = "stdout"
+	// Stderr represents standard error
+	Stderr Stream = "stderr"
+	// Stdin represents standard input
+	Stdin Stream = "stdin"
+)
+
+// ChunkListener represents output listener, it receives raw chunks tagged with the stream they were read from;
+// the listener is called synchronously, so a slow listener slows reading down
+type ChunkListener func(stream Stream, chunk string)
@@ -0,0 +1,70 @@
+//go:build !windows
+// +build !windows
+
+package local
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/viant/gosh/runner"
+)
+
+// signal sends signal to the shell process group members other than the shell itself
+func (r *Runner) signal(sig os.Signal) error {
+	signal, ok := sig.(syscall.Signal)
+	if !ok {
+		return runner.ErrSignalNotSupported
+	}
+	shell := r.cmd.Process.Pid
+	members, err := groupMembers(shell)
+	if err != nil {
+		return err
+	}
+	for _, pid := range members {
+		if pid != shell {
+			_ = syscall.Kill(pid, signal)
+		}
+	}
+	return nil
+}
+
+// groupMembers returns ids of processes that belong to the process group, /proc is used when available, ps otherwise
+func groupMembers(pgid int) ([]int, error) {
+	var result []int
+	if entries, err := os.ReadDir("/proc"); err == nil {
+		for _, entry := range entries {
+			pid, err := strconv.Atoi(entry.Name())
+			if err != nil {
+				continue
+			}
+			stat, err := os.ReadFile("/proc/" + entry.Name() + "/stat")
+			if err != nil {
+				continue
+			}
+			// pid (comm) state ppid pgrp ..., comm may contain spaces and parentheses
+			fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+			if len(fields) > 2 && fields[2] == strconv.Itoa(pgid) {
+				result = append(result, pid)
+			}
+		}
+		return result, nil
+	}
+	output, err := exec.Command("ps", "-A", "-o", "pid=", "-o", "pgid=").Output()
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[1] != strconv.Itoa(pgid) {
+			continue
+		}
+		if pid, err := strconv.Atoi(fields[0]); err == nil {
+			result = append(result, pid)
+		}
+	}
+	return result, nil
+}
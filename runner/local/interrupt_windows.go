@@ -0,0 +1,15 @@
+//go:build windows
+// +build windows
+
+package local
+
+import (
+	"os"
+
+	"github.com/viant/gosh/runner"
+)
+
+// signal is not supported on Windows, a command that does not complete is stopped by restarting the shell
+func (r *Runner) signal(sig os.Signal) error {
+	return runner.ErrSignalNotSupported
+}
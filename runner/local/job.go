@@ -0,0 +1,70 @@
+package local
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/viant/gosh/runner"
+)
+
+// Start starts supplied command as a separate process group in the session working directory,
+// the session stays usable while the job runs; the job is killed when ctx is done before it finishes
+func (r *Runner) Start(ctx context.Context, command string, options ...runner.Option) (*runner.Job, error) {
+	cmd := exec.Command(r.options.Shell, shellArgs(r.options.Shell, command)...)
+	cmd.SysProcAttr = newSysProcAttr()
+	cmd.Env = r.buildEnv()
+	cmd.Dir = r.options.Path
+	if r.pipeline != nil {
+		if workdir := r.pipeline.WorkingDirectory(); workdir != "" {
+			cmd.Dir = workdir
+		}
+	}
+	job := runner.NewJob(command, func(sig os.Signal) error {
+		return signalGroup(cmd.Process, sig)
+	})
+	var copies sync.WaitGroup
+	var err error
+	if cmd.Stdout, err = pipe(job.Writer(runner.Stdout), &copies); err != nil {
+		return nil, err
+	}
+	if cmd.Stderr, err = pipe(job.Writer(runner.Stderr), &copies); err != nil {
+		_ = cmd.Stdout.(io.Closer).Close()
+		return nil, err
+	}
+	err = cmd.Start()
+	_ = cmd.Stdout.(io.Closer).Close()
+	_ = cmd.Stderr.(io.Closer).Close()
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		code, err := waitGroup(cmd, &copies)
+		job.Finish(code, err)
+	}()
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = job.Kill()
+		case <-job.Done():
+		}
+	}()
+	return job, nil
+}
+
+// pipe returns pipe writer, data written to it is copied to dest until all writers are closed
+func pipe(dest io.Writer, copies *sync.WaitGroup) (*os.File, error) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	copies.Add(1)
+	go func() {
+		defer copies.Done()
+		_, _ = io.Copy(dest, reader)
+		_ = reader.Close()
+	}()
+	return writer, nil
+}
@@ -0,0 +1,43 @@
+//go:build !windows
+// +build !windows
+
+package local
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+
+	"github.com/viant/gosh/internal/proctrack"
+	"github.com/viant/gosh/runner"
+)
+
+// shellArgs returns shell arguments running command
+func shellArgs(shell, command string) []string {
+	return []string{"-c", command}
+}
+
+// signalGroup sends signal to the whole process group
+func signalGroup(process *os.Process, sig os.Signal) error {
+	signal, ok := sig.(syscall.Signal)
+	if !ok {
+		return runner.ErrSignalNotSupported
+	}
+	return syscall.Kill(-process.Pid, signal)
+}
+
+// waitGroup waits until every process of the group has exited and its output was copied, it returns the leader exit code
+func waitGroup(cmd *exec.Cmd, copies *sync.WaitGroup) (int, error) {
+	pid := cmd.Process.Pid
+	done := proctrack.RegisterGroup(pid)
+	copies.Wait()
+	proctrack.Check() // members writing output are usually gone once the output is closed
+	<-done
+	code, ok := proctrack.ExitStatus(pid)
+	if !ok {
+		return -1, fmt.Errorf("exit status of process %v is not available", pid)
+	}
+	return code, nil
+}
@@ -0,0 +1,42 @@
+//go:build windows
+// +build windows
+
+package local
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/viant/gosh/runner"
+)
+
+// shellArgs returns shell arguments running command
+func shellArgs(shell, command string) []string {
+	shell = strings.ToLower(shell)
+	if strings.Contains(shell, "powershell") || strings.Contains(shell, "pwsh") {
+		return []string{"-Command", command}
+	}
+	return []string{"/C", command}
+}
+
+// signalGroup kills the process, other signals are not supported on Windows
+func signalGroup(process *os.Process, sig os.Signal) error {
+	if sig != os.Kill {
+		return runner.ErrSignalNotSupported
+	}
+	return process.Kill()
+}
+
+// waitGroup waits for the process and its output and returns its exit code
+func waitGroup(cmd *exec.Cmd, copies *sync.WaitGroup) (int, error) {
+	err := cmd.Wait()
+	copies.Wait()
+	var exitErr *exec.ExitError
+	if err == nil || errors.As(err, &exitErr) {
+		return cmd.ProcessState.ExitCode(), nil
+	}
+	return -1, err
+}
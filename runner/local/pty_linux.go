@@ -0,0 +1,51 @@
+package local
+
+import (
+	"io"
+	"os"
+	"syscall"
+
+	"github.com/creack/pty"
+	"golang.org/x/sys/unix"
+)
+
+// startPty starts the shell on a pseudo terminal with echo disabled as on ssh, stdout and stderr share the terminal
+func (r *Runner) startPty() (stdout, stderr io.Reader, err error) {
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tty.Close()
+	cols, rows := r.Size()
+	if err = resizePty(ptmx, cols, rows); err != nil {
+		_ = ptmx.Close()
+		return nil, nil, err
+	}
+	termios, err := unix.IoctlGetTermios(int(tty.Fd()), unix.TCGETS)
+	if err == nil {
+		termios.Lflag &^= unix.ECHO
+		err = unix.IoctlSetTermios(int(tty.Fd()), unix.TCSETS, termios)
+	}
+	if err != nil {
+		_ = ptmx.Close()
+		return nil, nil, err
+	}
+	r.cmd.Env = append(r.cmd.Env, "TERM="+r.options.Term)
+	r.cmd.Stdin, r.cmd.Stdout, r.cmd.Stderr = tty, tty, tty
+	// the shell leads a new session, so its process group is still its pid
+	r.cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+	if err = r.cmd.Start(); err != nil {
+		_ = ptmx.Close()
+		return nil, nil, err
+	}
+	r.mux.Lock()
+	r.pty, r.stdin = ptmx, ptmx
+	r.mux.Unlock()
+	// stderr is written to the terminal, the pipeline reads an empty stream that ends when it is closed
+	stderr, _ = io.Pipe()
+	return ptmx, stderr, nil
+}
+
+func resizePty(ptmx *os.File, cols, rows int) error {
+	return pty.Setsize(ptmx, &pty.Winsize{Cols: uint16(cols), Rows: uint16(rows)})
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package local
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+)
+
+// startPty returns an error, pty mode is supported on linux only
+func (r *Runner) startPty() (stdout, stderr io.Reader, err error) {
+	return nil, nil, fmt.Errorf("pty mode is not supported on %v", runtime.GOOS)
+}
+
+func resizePty(ptmx *os.File, cols, rows int) error {
+	return fmt.Errorf("pty mode is not supported on %v", runtime.GOOS)
+}
@@ -7,6 +7,8 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -18,7 +20,11 @@ type Runner struct {
 	options  *runner.Options
 	pipeline *runner.Pipeline
 	stdin    io.WriteCloser
+	pty      *os.File
 	counter  int32
+	mux      sync.Mutex
+	cols     int // terminal size guarded by mux, options are read by the pipeline without it
+	rows     int
 }
 
 // Send sends data to stdin
@@ -41,12 +47,27 @@ func (r *Runner) ensureCommandStarted() {
 
 // Run runs supplied command
 func (r *Runner) Run(ctx context.Context, command string, options ...runner.Option) (string, int, error) {
+	result, err := r.RunResult(ctx, command, options...)
+	if result == nil {
+		return "", 0, err
+	}
+	return result.Output(), result.ExitCode, err
+}
 
+// RunResult runs supplied command and returns its result
+func (r *Runner) RunResult(ctx context.Context, command string, options ...runner.Option) (*runner.Result, error) {
 	if err := r.initIfNeeded(ctx); err != nil {
-		return "", 0, err
+		return nil, err
 	}
 	if !r.pipeline.Running() {
-		return "", 0, r.pipeline.Err()
+		return nil, &runner.ConnectionError{Command: command, Host: runner.LocalHost, Cause: r.pipeline.Err()}
+	}
+	r.pipeline.Await(ctx, options...)
+	if !r.pipeline.Completed() { // command stopped at a terminator did not complete
+		r.interrupt()
+		if err := r.initIfNeeded(ctx); err != nil {
+			return nil, err
+		}
 	}
 	r.pipeline.Drain(ctx)
 
@@ -54,27 +75,64 @@ func (r *Runner) Run(ctx context.Context, command string, options ...runner.Opti
 		return r.runAsPipeline(ctx, command, options)
 	}
 
-	err := r.runCommand(command)
+	startedAt := time.Now()
+	err := r.runCommand(command, options)
 	atomic.AddInt32(&r.counter, 1)
 	if err != nil {
-		return "", 0, err
+		return nil, err
+	}
+	result, _, err := r.pipeline.ReadResult(ctx, options...)
+	if result != nil {
+		result.StartedAt = startedAt
+		result.Duration = time.Since(startedAt)
+	}
+	if !r.pipeline.Completed() {
+		r.interrupt()
 	}
-	output, _, code, err := r.pipeline.Read(ctx, options...)
+	err = r.options.Apply(options).Complete(command, runner.LocalHost, result, err)
 	if r.options.History != nil {
-		r.options.History.Commands = append(r.options.History.Commands, runner.NewCommand(command, output, err))
+		entry := runner.NewResultCommand(command, result, err)
+		entry.Host, entry.Dir = runner.LocalHost, r.pipeline.WorkingDirectory()
+		_ = r.options.History.Append(entry) // store errors are reported by History.Err
 	}
-	return output, code, err
+	return result, err
 }
 
-func (r *Runner) runAsPipeline(ctx context.Context, command string, options []runner.Option) (string, int, error) {
+// interrupt stops the command that did not complete, the shell is restarted if it can not be resynchronised;
+// a restarted shell starts in the last working directory, variables exported by earlier commands are lost
+func (r *Runner) interrupt() {
+	if err := r.pipeline.Interrupt(r.stdin, r.signal); err != nil {
+		_ = r.Close()
+		atomic.StoreUint32(&r.inited, 0)
+	}
+}
+
+func (r *Runner) runAsPipeline(ctx context.Context, command string, options []runner.Option) (*runner.Result, error) {
+	result := &runner.Result{ExitCode: -1, StartedAt: time.Now()}
 	cmd := runner.EnsureLineTermination(command)
 	_, err := r.stdin.Write([]byte(cmd))
 	atomic.AddInt32(&r.counter, 1)
 	if err != nil {
-		return "", -1, err
+		return result, err
 	}
 	err = r.pipeline.Listen(ctx, options...)
-	return "", -1, err
+	result.Duration = time.Since(result.StartedAt)
+	return result, err
+}
+
+// Waiting returns true if a command run with runner.WithInteractive is running and reads the shell stdin
+func (r *Runner) Waiting() bool {
+	return atomic.LoadUint32(&r.inited) == 1 && r.pipeline != nil && r.pipeline.Waiting()
+}
+
+// Host returns runner.LocalHost
+func (r *Runner) Host() string {
+	return runner.LocalHost
+}
+
+// History returns command history set with runner.WithHistory
+func (r *Runner) History() *runner.History {
+	return r.options.History
 }
 
 // PID returns process id
@@ -85,11 +143,11 @@ func (r *Runner) PID() int {
 	return r.cmd.Process.Pid
 }
 
-func (r *Runner) runCommand(command string) error {
-	var cmd = r.pipeline.FormatCmd(command)
+func (r *Runner) runCommand(command string, options []runner.Option) error {
+	var cmd = r.pipeline.FormatCmd(command, options...)
 	_, err := r.stdin.Write([]byte(cmd))
 	if err != nil {
-		return fmt.Errorf("failed to execute command: %v, err: %v", command, err)
+		return &runner.ConnectionError{Command: command, Host: runner.LocalHost, Stream: runner.Stdin, Cause: err}
 	}
 	return nil
 }
@@ -99,6 +157,8 @@ func (r *Runner) initIfNeeded(ctx context.Context) error {
 		return nil
 	}
 	if err := r.init(ctx); err != nil {
+		_ = r.Close()
+		atomic.StoreUint32(&r.inited, 0)
 		return err
 	}
 	return nil
@@ -109,37 +169,59 @@ func (r *Runner) init(ctx context.Context) error {
 	// Apply OS-specific process attributes
 	r.cmd.SysProcAttr = newSysProcAttr()
 
-	// Working directory
+	// Working directory, a restarted shell continues in the directory the previous one completed the last command in
 	if r.options.Path != "" {
 		r.cmd.Dir = r.options.Path
 	}
+	if r.pipeline != nil {
+		if workdir := r.pipeline.WorkingDirectory(); workdir != "" {
+			if info, err := os.Stat(workdir); err == nil && info.IsDir() {
+				r.cmd.Dir = workdir
+			}
+		}
+	}
 
 	// Environment: start from current env, apply overrides, and extend PATH
 	r.cmd.Env = r.buildEnv()
+	var stdout, stderr io.Reader
 	var err error
-	r.stdin, err = r.cmd.StdinPipe()
-	if err != nil {
-		return err
+	if r.options.Pty() {
+		stdout, stderr, err = r.startPty()
+	} else {
+		stdout, stderr, err = r.startPipes()
 	}
-
-	stdout, err := r.cmd.StdoutPipe()
 	if err != nil {
 		return err
 	}
-	stderr, err := r.cmd.StderrPipe()
-	if err != nil {
+	if r.pipeline, err = runner.NewPipeline(ctx, r.stdin, stdout, stderr, r.options); err != nil {
 		return err
 	}
-	if err := r.cmd.Start(); err != nil {
-		return err
+	if r.options.Pty() {
+		// an interactive shell runs each command in its own process group, keep them in the shell group for signal;
+		// written through the pipeline, internal commands are not recorded in history
+		if err = r.runCommand("set +m", nil); err == nil {
+			_, _, err = r.pipeline.ReadResult(ctx)
+		}
 	}
-	r.pipeline, err = runner.NewPipeline(ctx, r.stdin, stdout, stderr, r.options)
 	return err
 }
 
+func (r *Runner) startPipes() (stdout, stderr io.Reader, err error) {
+	if r.stdin, err = r.cmd.StdinPipe(); err != nil {
+		return nil, nil, err
+	}
+	if stdout, err = r.cmd.StdoutPipe(); err != nil {
+		return nil, nil, err
+	}
+	if stderr, err = r.cmd.StderrPipe(); err != nil {
+		return nil, nil, err
+	}
+	return stdout, stderr, r.cmd.Start()
+}
+
 // Close closes runner
 func (r *Runner) Close() error {
-	if r.cmd.Process != nil {
+	if r.cmd != nil && r.cmd.Process != nil {
 		r.cmd.Process.Kill()
 	}
 	if r.pipeline != nil {
@@ -154,7 +236,12 @@ func (r *Runner) Close() error {
 // New creates a new local runner
 func New(options ...runner.Option) *Runner {
 	opts := runner.NewOptions(options)
-	return &Runner{options: opts}
+	if opts.Pty() {
+		// an interactive shell prints its prompt, use a distinct one that is removed from the output
+		options = append([]runner.Option{runner.WithShellPrompt("gosh-" + strconv.Itoa(int(time.Now().UnixMilli())) + "$")}, options...)
+		opts = runner.NewOptions(options)
+	}
+	return &Runner{options: opts, cols: opts.Cols, rows: opts.Rows}
 }
 
 // buildEnv constructs the environment for the shell process by:
@@ -3,7 +3,16 @@ package local
 import (
 	"context"
 	"github.com/stretchr/testify/assert"
+	"github.com/viant/gosh/runner"
+	"github.com/viant/scy/cred"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestService_Run(t *testing.T) {
@@ -15,3 +24,418 @@ func TestService_Run(t *testing.T) {
 	assert.True(t, runner.PID() > 0)
 
 }
+
+func TestRunner_RunResult(t *testing.T) {
+	runner := New()
+	defer runner.Close()
+	result, err := runner.RunResult(context.Background(), "echo out; echo err 1>&2; exit_code() { return 3; }; exit_code")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Contains(t, result.Stdout, "out")
+	assert.NotContains(t, result.Stdout, "err")
+	assert.Contains(t, result.Stderr, "err")
+	assert.False(t, result.StartedAt.IsZero())
+	assert.True(t, result.Duration > 0)
+}
+
+func TestRunner_History(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "history.jsonl")
+	history := runner.NewHistory(runner.WithHistoryStore(runner.NewFileStore(location)))
+	aRunner := New(runner.WithHistory(history))
+	defer aRunner.Close()
+	_, _, err := aRunner.Run(context.Background(), "cd /tmp && echo moved")
+	assert.Nil(t, err)
+	_, _, err = aRunner.Run(context.Background(), "exit_code() { return 2; }; exit_code")
+	assert.Nil(t, err)
+
+	loaded, err := runner.LoadHistory(runner.NewFileStore(location))
+	if !assert.Nil(t, err) || !assert.Len(t, loaded.Entries(), 2) {
+		return
+	}
+	first, second := loaded.Entries()[0], loaded.Entries()[1]
+	assert.Equal(t, []string{"moved"}, first.Stdout)
+	assert.Equal(t, runner.LocalHost, first.Host)
+	assert.Equal(t, "/tmp", first.Dir)
+	assert.False(t, first.FinishedAt.Before(first.StartedAt))
+	assert.Equal(t, 2, second.ExitCode)
+	assert.Equal(t, 1, second.Index)
+}
+
+func TestRunner_Sentinel(t *testing.T) {
+	runner := New()
+	defer runner.Close()
+	var testCases = []struct {
+		description string
+		command     string
+		expect      string
+		code        int
+	}{
+		{description: "legacy status marker", command: "echo status:0; echo done; exit_code() { return 4; }; exit_code", expect: "status:0\ndone", code: 4},
+		{description: "foreign nonce marker", command: "echo __GOSH_0000__:0; echo done", expect: "__GOSH_0000__:0\ndone", code: 0},
+		{description: "no trailing line break", command: "printf abc", expect: "abc", code: 0},
+	}
+	for _, testCase := range testCases {
+		output, code, err := runner.Run(context.Background(), testCase.command)
+		assert.Nil(t, err, testCase.description)
+		assert.Equal(t, testCase.expect, output, testCase.description)
+		assert.Equal(t, testCase.code, code, testCase.description)
+	}
+}
+
+func TestRunner_Events(t *testing.T) {
+	aRunner := New()
+	defer aRunner.Close()
+	var stdout, stderr string
+	var lines []string
+	var last *runner.Event
+	ids := map[string]bool{}
+	for event := range runner.Events(context.Background(), aRunner, "echo one; echo err 1>&2; printf two; false") {
+		ids[event.CommandID] = true
+		assert.False(t, event.Time.IsZero())
+		switch event.Type {
+		case runner.EventStdout:
+			stdout += event.Data
+		case runner.EventStderr:
+			stderr += event.Data
+		case runner.EventLine:
+			lines = append(lines, string(event.Stream)+":"+event.Data)
+		}
+		last = event
+	}
+	assert.Equal(t, "one\ntwo", stdout)
+	assert.Equal(t, "err\n", stderr)
+	assert.ElementsMatch(t, []string{"stdout:one", "stdout:two", "stderr:err"}, lines)
+	assert.Len(t, ids, 1)
+	if assert.NotNil(t, last) && assert.Equal(t, runner.EventExit, last.Type) {
+		assert.Equal(t, 1, last.ExitCode)
+	}
+}
+
+func TestRunner_Start(t *testing.T) {
+	ctx := context.Background()
+	aRunner := New()
+	defer aRunner.Close()
+	_, _, err := aRunner.Run(ctx, "cd /tmp")
+	if !assert.Nil(t, err) {
+		return
+	}
+	job, err := aRunner.Start(ctx, "pwd; (sleep 0.3; echo child) & echo err 1>&2; exit 3")
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, ok := job.ExitCode()
+	assert.False(t, ok)
+	output, _, err := aRunner.Run(ctx, "echo session")
+	assert.Nil(t, err)
+	assert.Equal(t, "session", output)
+
+	result, err := job.Wait(ctx)
+	if !assert.Nil(t, err) {
+		return
+	}
+	code, ok := job.ExitCode()
+	assert.True(t, ok)
+	assert.Equal(t, 3, code)
+	assert.Equal(t, "/tmp\nchild\n", result.Stdout)
+	assert.Equal(t, "err\n", result.Stderr)
+	assert.True(t, result.Duration >= 300*time.Millisecond)
+	stdout, _ := job.Output()
+	assert.Equal(t, "/tmp\nchild\n", stdout)
+	stdout, _ = job.Output()
+	assert.Equal(t, "", stdout)
+
+	job, err = aRunner.Start(ctx, "exec sleep 10")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, job.Signal(syscall.SIGTERM))
+	waitCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	result, err = job.Wait(waitCtx)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 128+int(syscall.SIGTERM), result.ExitCode)
+	}
+}
+
+func TestRunner_Restart(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	aRunner := New()
+	defer aRunner.Close()
+	_, _, err := aRunner.Run(ctx, "cd "+dir)
+	if !assert.Nil(t, err) {
+		return
+	}
+	shell := aRunner.options.Shell
+	_ = aRunner.Close() // shell restarted after it could not be resynchronised by interrupt
+	atomic.StoreUint32(&aRunner.inited, 0)
+	aRunner.options.Shell = filepath.Join(dir, "missing")
+	_, _, err = aRunner.Run(ctx, "pwd")
+	assert.NotNil(t, err)
+	assert.EqualValues(t, 0, atomic.LoadUint32(&aRunner.inited))
+
+	aRunner.options.Shell = shell
+	output, _, err := aRunner.Run(ctx, "pwd")
+	assert.Nil(t, err)
+	assert.Equal(t, dir, strings.TrimSpace(output))
+}
+
+func TestRunner_Interrupt(t *testing.T) {
+	aRunner := New()
+	defer aRunner.Close()
+	var testCases = []struct {
+		description string
+		command     string
+		cancel      bool
+		deadline    bool
+		expectErr   error
+	}{
+		{description: "timeout", command: "echo started; sleep 30; echo late", expectErr: runner.ErrTimeout},
+		{description: "context cancel", command: "sleep 30; echo late", cancel: true, expectErr: context.Canceled},
+		{description: "context deadline", command: "sleep 30; echo late", deadline: true, expectErr: runner.ErrTimeout},
+		{description: "SIGINT ignored", command: "(trap '' INT; exec sleep 30); echo late", expectErr: runner.ErrTimeout},
+	}
+	for _, testCase := range testCases {
+		ctx, cancel := context.WithCancel(context.Background())
+		options := []runner.Option{runner.WithTimeout(300)}
+		switch {
+		case testCase.cancel:
+			options = nil
+			time.AfterFunc(300*time.Millisecond, cancel)
+		case testCase.deadline:
+			options = nil
+			cancel()
+			ctx, cancel = context.WithTimeout(context.Background(), 300*time.Millisecond)
+		}
+		startedAt := time.Now()
+		_, err := aRunner.RunResult(ctx, testCase.command, options...)
+		cancel()
+		assert.ErrorIs(t, err, testCase.expectErr, testCase.description)
+		assert.True(t, time.Since(startedAt) < 5*time.Second, testCase.description)
+		output, code, err := aRunner.Run(context.Background(), "echo next")
+		assert.Nil(t, err, testCase.description)
+		assert.Equal(t, 0, code, testCase.description)
+		assert.Equal(t, "next", output, testCase.description)
+	}
+	result, err := aRunner.RunResult(context.Background(), "echo started; sleep 30", runner.WithTimeout(300))
+	timeoutErr := &runner.TimeoutError{}
+	if assert.ErrorAs(t, err, &timeoutErr) {
+		assert.Equal(t, "echo started; sleep 30", timeoutErr.Command)
+		assert.Equal(t, runner.LocalHost, timeoutErr.Host)
+		assert.Equal(t, runner.Stdout, timeoutErr.Stream)
+		assert.Equal(t, "started\n", timeoutErr.Stdout)
+	}
+	assert.Equal(t, -1, result.ExitCode)
+}
+
+func TestRunner_Terminator(t *testing.T) {
+	ctx := context.Background()
+	location := filepath.Join(t.TempDir(), "answer")
+	aRunner := New(runner.WithTerminators([]string{"Password:"}))
+	defer aRunner.Close()
+
+	startedAt := time.Now()
+	output, code, err := aRunner.Run(ctx, "printf 'Password: '; read answer; echo $answer > "+location+"; sleep 1", runner.WithInteractive())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "Password: ", output)
+	assert.True(t, time.Since(startedAt) < time.Second)
+	assert.True(t, aRunner.Waiting())
+
+	_, err = aRunner.Send(ctx, []byte("secret\n"))
+	assert.Nil(t, err)
+	output, code, err = aRunner.Run(ctx, "cat "+location) // awaits the stopped command
+	assert.Nil(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "secret", output)
+	assert.False(t, aRunner.Waiting())
+
+	perCall := New()
+	defer perCall.Close()
+	output, _, err = perCall.Run(ctx, "printf 'Continue? '; sleep 1; echo late", runner.WithTerminators([]string{"Continue?"}))
+	assert.Nil(t, err)
+	assert.Equal(t, "Continue? ", output)
+}
+
+func TestRunner_ExitError(t *testing.T) {
+	aRunner := New(runner.WithExitError())
+	defer aRunner.Close()
+	_, code, err := aRunner.Run(context.Background(), "echo failed 1>&2; false")
+	assert.Equal(t, 1, code)
+	exitErr := &runner.ExitError{}
+	if assert.ErrorAs(t, err, &exitErr) {
+		assert.Equal(t, 1, exitErr.ExitCode)
+		assert.Equal(t, "failed\n", exitErr.Stderr)
+	}
+	_, _, err = aRunner.Run(context.Background(), "true")
+	assert.Nil(t, err)
+}
+
+// fakeSudo emulates sudo accepting password "secret" and caching the ticket in $TICKET
+const fakeSudo = `#!/bin/sh
+nonInteractive=0; validate=0; user=root
+while [ $# -gt 0 ]; do
+	case $1 in
+	-n) nonInteractive=1;; -S) ;; -v) validate=1;; -p) shift; prompt=$1;; -u) shift; user=$1;; --) shift; break;; *) break;;
+	esac
+	shift
+done
+if [ ! -f "$TICKET" ]; then
+	if [ $nonInteractive = 1 ]; then echo "sudo: a password is required" 1>&2; exit 1; fi
+	printf '%s' "$prompt" 1>&2
+	while read password && [ "$password" != secret ]; do echo "Sorry, try again." 1>&2; printf '%s' "$prompt" 1>&2; done
+	if [ "$password" != secret ]; then echo "sudo: 1 incorrect password attempt" 1>&2; exit 1; fi
+	touch "$TICKET"
+fi
+[ $validate = 1 ] && exit 0
+echo "as $user"
+exec "$@"
+`
+
+func TestRunner_Sudo(t *testing.T) {
+	dir := t.TempDir()
+	if !assert.Nil(t, os.WriteFile(filepath.Join(dir, "sudo"), []byte(fakeSudo), 0755)) {
+		return
+	}
+	ctx := context.Background()
+	aRunner := New(runner.WithEnvironment(map[string]string{"PATH": dir + ":" + os.Getenv("PATH"), "TICKET": filepath.Join(dir, "ticket")}))
+	defer aRunner.Close()
+
+	_, _, err := aRunner.Run(ctx, "id -u", runner.WithSudo(""))
+	privilegeErr := &runner.PrivilegeError{}
+	if assert.ErrorAs(t, err, &privilegeErr) {
+		assert.Equal(t, "id -u", privilegeErr.Command)
+		assert.Equal(t, "root", privilegeErr.User)
+	}
+	assert.ErrorIs(t, err, runner.ErrPasswordRequired)
+
+	_, _, err = aRunner.Run(ctx, "id -u", runner.WithPrivilege(&runner.Privilege{Credential: &cred.Basic{Password: "wrong"}}))
+	assert.ErrorIs(t, err, runner.ErrAuthentication)
+	output, _, err := aRunner.Run(ctx, "echo next") // sudo prompting again does not read the next command
+	assert.Nil(t, err)
+	assert.Equal(t, "next", output)
+
+	output, code, err := aRunner.Run(ctx, "echo 'it''s' | cat", runner.WithPrivilege(&runner.Privilege{User: "postgres", Credential: &cred.Basic{Password: "secret"}}))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "as postgres\nits", output)
+
+	output, _, err = aRunner.Run(ctx, "echo cached", runner.WithSudo("")) // ticket cached, no password needed
+	assert.Nil(t, err)
+	assert.Equal(t, "as root\ncached", output)
+}
+
+const fakeSu = `#!/bin/sh
+if [ ! -f "$ROOT" ]; then
+	printf 'Password: '
+	read password
+	if [ "$password" != secret ]; then echo "su: Authentication failure" 1>&2; exit 1; fi
+fi
+exec sh -c "$4"
+`
+
+func TestRunner_Su(t *testing.T) {
+	dir := t.TempDir()
+	if !assert.Nil(t, os.WriteFile(filepath.Join(dir, "su"), []byte(fakeSu), 0755)) {
+		return
+	}
+	ctx := context.Background()
+	root := filepath.Join(dir, "root")
+	aRunner := New(runner.WithEnvironment(map[string]string{"PATH": dir + ":" + os.Getenv("PATH"), "ROOT": root}))
+	defer aRunner.Close()
+	su := func(password string) runner.Option {
+		return runner.WithPrivilege(&runner.Privilege{Strategy: runner.EscalationSu, Credential: &cred.Basic{Password: password}})
+	}
+
+	output, code, err := aRunner.Run(ctx, "echo escalated", runner.WithInteractive(), su("secret"))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "escalated", output)
+
+	_, _, err = aRunner.Run(ctx, "echo escalated", runner.WithInteractive(), su("wrong"))
+	assert.ErrorIs(t, err, runner.ErrAuthentication)
+
+	if !assert.Nil(t, os.WriteFile(root, nil, 0644)) { // su does not prompt, the command prompt is not answered with the password
+		return
+	}
+	once := sync.Once{}
+	typeLine := func(stream runner.Stream, chunk string) {
+		if strings.Contains(chunk, "password:") {
+			once.Do(func() { go aRunner.Send(ctx, []byte("typed\n")) })
+		}
+	}
+	output, _, err = aRunner.Run(ctx, "printf 'password: '; read line; echo got $line", runner.WithInteractive(), su("secret"), runner.WithChunkListener(typeLine))
+	assert.Nil(t, err)
+	assert.Equal(t, "password: got typed", output)
+}
+
+func TestRunner_Pty(t *testing.T) {
+	ctx := context.Background()
+	history := runner.NewHistory()
+	aRunner := New(runner.WithPty(), runner.WithTerm("vt100"), runner.WithHistory(history))
+	defer aRunner.Close()
+	output, code, err := aRunner.Run(ctx, "test -t 1 && test -t 2 && echo $TERM")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "vt100", strings.TrimSpace(output))
+	if assert.Equal(t, 1, history.Len()) { // shell setup is not recorded
+		assert.Equal(t, "test -t 1 && test -t 2 && echo $TERM", history.Entries()[0].Stdin)
+	}
+
+	output, code, err = aRunner.Run(ctx, "echo err 1>&2; exit_code() { return 3; }; exit_code")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, code)
+	assert.Equal(t, "err", strings.TrimSpace(output))
+
+	once := sync.Once{}
+	typeLine := func(stream runner.Stream, chunk string) { // input sent before the command is read would run as a command
+		if strings.Contains(chunk, "ready") {
+			once.Do(func() { go aRunner.Send(ctx, []byte("typed\n")) })
+		}
+	}
+	output, _, err = aRunner.Run(ctx, "echo ready; read line; echo got $line", runner.WithInteractive(), runner.WithChunkListener(typeLine))
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(strings.TrimSpace(output), "got typed"), output)
+
+	_, err = aRunner.RunResult(ctx, "sleep 30", runner.WithTimeout(300))
+	assert.ErrorIs(t, err, runner.ErrTimeout)
+	output, _, err = aRunner.Run(ctx, "echo next")
+	assert.Nil(t, err)
+	assert.Equal(t, "next", strings.TrimSpace(output))
+}
+
+func TestRunner_Resize(t *testing.T) {
+	ctx := context.Background()
+	aRunner := New(runner.WithPty(), runner.WithTermSize(80, 24))
+	defer aRunner.Close()
+	output, _, err := aRunner.Run(ctx, "stty size </dev/tty")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "24 80", strings.TrimSpace(output))
+	assert.Nil(t, aRunner.Resize(120, 40))
+	cols, rows := aRunner.Size()
+	assert.Equal(t, 120, cols)
+	assert.Equal(t, 40, rows)
+	output, _, err = aRunner.Run(ctx, "stty size </dev/tty")
+	assert.Nil(t, err)
+	assert.Equal(t, "40 120", strings.TrimSpace(output))
+
+	resized := make(chan struct{})
+	go func() { // resize while the pipeline reads the result
+		defer close(resized)
+		for i := 0; i < 20; i++ {
+			_ = aRunner.Resize(100+i, 30)
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+	_, _, err = aRunner.Run(ctx, "sleep 0.2")
+	assert.Nil(t, err)
+	<-resized
+
+	assert.ErrorIs(t, New().Resize(120, 40), runner.ErrResizeNotSupported)
+}
@@ -0,0 +1,34 @@
+package local
+
+import (
+	"fmt"
+
+	"github.com/viant/gosh/runner"
+)
+
+// Resize changes the pseudo terminal size, it returns runner.ErrResizeNotSupported unless the runner uses runner.WithPty
+func (r *Runner) Resize(cols, rows int) error {
+	if !r.options.Pty() {
+		return runner.ErrResizeNotSupported
+	}
+	if cols <= 0 || rows <= 0 {
+		return fmt.Errorf("invalid terminal size: %vx%v", cols, rows)
+	}
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.cols, r.rows = cols, rows
+	if r.pty == nil {
+		return nil
+	}
+	return resizePty(r.pty, cols, rows)
+}
+
+// Size returns the pseudo terminal size, zero unless the runner uses runner.WithPty
+func (r *Runner) Size() (cols, rows int) {
+	if !r.options.Pty() {
+		return 0, 0
+	}
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return r.cols, r.rows
+}
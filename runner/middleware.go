@@ -0,0 +1,264 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redactedMask replaces redacted text
+const redactedMask = "******"
+
+type (
+	// RunFunc represents a function running a command
+	RunFunc func(ctx context.Context, command string, options ...Option) (*Result, error)
+
+	// Middleware represents a run interceptor, it calls next to run the command or returns without running it
+	Middleware func(next RunFunc) RunFunc
+
+	// Observer is notified of a completed command, result may be nil if the command did not run
+	Observer func(command string, elapsed time.Duration, result *Result, err error)
+
+	// wrapper represents a runner running commands through middleware and checking sent data with send hooks
+	wrapper struct {
+		runner      Runner
+		middlewares []Middleware
+		run         RunFunc
+		hooks       []SendHook
+		mux         sync.Mutex
+		pending     string // sent data held until its lines are complete
+	}
+)
+
+// Run runs supplied command through the middleware
+func (w *wrapper) Run(ctx context.Context, command string, options ...Option) (string, int, error) {
+	result, err := w.run(ctx, command, options...)
+	if result == nil {
+		return "", 0, err
+	}
+	return result.Output(), result.ExitCode, err
+}
+
+// RunResult runs supplied command through the middleware and returns its result
+func (w *wrapper) RunResult(ctx context.Context, command string, options ...Option) (*Result, error) {
+	return w.run(ctx, command, options...)
+}
+
+// Send sends data to stdin of the underlying runner, it is not passed through the middleware. With send hooks, data the shell
+// reads as commands is held until its lines are complete and every hook accepts them, held data is reported as sent, see WrapSend
+func (w *wrapper) Send(ctx context.Context, data []byte) (int, error) {
+	if len(w.hooks) == 0 || Waiting(w.runner) {
+		return w.runner.Send(ctx, data)
+	}
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	w.pending += string(data)
+	end := strings.LastIndexByte(w.pending, '\n') + 1
+	if end == 0 {
+		return len(data), nil
+	}
+	input := w.pending[:end]
+	for _, hook := range w.hooks {
+		if err := hook(ctx, input); err != nil {
+			if errors.Is(err, ErrIncomplete) {
+				return len(data), nil
+			}
+			w.pending = ""
+			return 0, err
+		}
+	}
+	w.pending = w.pending[end:]
+	if _, err := w.runner.Send(ctx, []byte(input)); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Waiting returns true if a command of the underlying runner reads the shell stdin
+func (w *wrapper) Waiting() bool {
+	return Waiting(w.runner)
+}
+
+// PID returns process id of the underlying runner
+func (w *wrapper) PID() int {
+	return w.runner.PID()
+}
+
+// Close closes the underlying runner
+func (w *wrapper) Close() error {
+	return w.runner.Close()
+}
+
+// Resize resizes the terminal of the underlying runner
+func (w *wrapper) Resize(cols, rows int) error {
+	return Resize(w.runner, cols, rows)
+}
+
+// Size returns the terminal size of the underlying runner
+func (w *wrapper) Size() (cols, rows int) {
+	return Size(w.runner)
+}
+
+// Host returns host of the underlying runner
+func (w *wrapper) Host() string {
+	return HostOf(w.runner)
+}
+
+// History returns command history of the underlying runner
+func (w *wrapper) History() *History {
+	return HistoryOf(w.runner)
+}
+
+// Start starts supplied command in the background through the middleware, which sees an empty result once the job started;
+// job output is not passed through the middleware. The job is started by the underlying JobRunner,
+// other runners run it through the middleware on their session
+func (w *wrapper) Start(ctx context.Context, command string, options ...Option) (*Job, error) {
+	jobRunner, ok := w.runner.(JobRunner)
+	if !ok {
+		return startOnSession(ctx, w, command, options...)
+	}
+	var job *Job
+	start := chain(func(ctx context.Context, command string, options ...Option) (*Result, error) {
+		var err error
+		if job, err = jobRunner.Start(ctx, command, options...); err != nil {
+			return nil, err
+		}
+		return &Result{StartedAt: job.StartedAt}, nil
+	}, w.middlewares)
+	if _, err := start(ctx, command, options...); err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, fmt.Errorf("job %q was not started by the middleware", command)
+	}
+	return job, nil
+}
+
+// Wrap returns a runner running commands through the middleware, the first middleware is the outermost.
+// Send, PID and Close are passed through, see WrapSend to check sent data; Start starts background jobs through the middleware too
+func Wrap(runner Runner, middlewares ...Middleware) Runner {
+	if len(middlewares) == 0 {
+		return runner
+	}
+	run := chain(func(ctx context.Context, command string, options ...Option) (*Result, error) {
+		return RunResult(ctx, runner, command, options...)
+	}, middlewares)
+	return &wrapper{runner: runner, middlewares: middlewares, run: run}
+}
+
+// chain returns run wrapped with the middleware, the first middleware is the outermost
+func chain(run RunFunc, middlewares []Middleware) RunFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		run = middlewares[i](run)
+	}
+	return run
+}
+
+// Logging logs each command with its exit code and duration, failed commands are logged as errors
+func Logging(logger *slog.Logger) Middleware {
+	return Observe(func(command string, elapsed time.Duration, result *Result, err error) {
+		switch {
+		case err != nil:
+			logger.Error("command failed", "command", command, "duration", elapsed, "error", err)
+		case result != nil && result.ExitCode != 0:
+			logger.Warn("command exited", "command", command, "duration", elapsed, "exitCode", result.ExitCode)
+		default:
+			logger.Info("command completed", "command", command, "duration", elapsed)
+		}
+	})
+}
+
+// Observe notifies the observer of each completed command, e.g. to collect metrics
+func Observe(observer Observer) Middleware {
+	return func(next RunFunc) RunFunc {
+		return func(ctx context.Context, command string, options ...Option) (*Result, error) {
+			startedAt := time.Now()
+			result, err := next(ctx, command, options...)
+			observer(command, time.Since(startedAt), result, err)
+			return result, err
+		}
+	}
+}
+
+// Redact masks text matching any of the expressions in the result, in output passed to listeners of the call and in output
+// kept by typed errors; output is streamed in chunks, a match split across chunks is not masked.
+// History is recorded by the runner beneath the middleware and is not masked, use WithHistoryRedact for it
+func Redact(expressions ...*regexp.Regexp) Middleware {
+	redact := func(text string) string {
+		return redact(text, expressions)
+	}
+	return func(next RunFunc) RunFunc {
+		return func(ctx context.Context, command string, options ...Option) (*Result, error) {
+			callOptions := (&Options{}).Apply(options)
+			if listener := callOptions.listener; listener != nil {
+				options = append(options, WithListener(func(stdout string, hasMore bool) {
+					listener(redact(stdout), hasMore)
+				}))
+			}
+			if listener := callOptions.chunkListener; listener != nil {
+				options = append(options, WithChunkListener(func(stream Stream, chunk string) {
+					listener(stream, redact(chunk))
+				}))
+			}
+			result, err := next(ctx, command, options...)
+			if result != nil {
+				result.Stdout, result.Stderr = redact(result.Stdout), redact(result.Stderr)
+			}
+			var exitErr *ExitError
+			var timeoutErr *TimeoutError
+			var connectionErr *ConnectionError
+			switch {
+			case errors.As(err, &exitErr):
+				exitErr.Stdout, exitErr.Stderr = redact(exitErr.Stdout), redact(exitErr.Stderr)
+			case errors.As(err, &timeoutErr):
+				timeoutErr.Stdout, timeoutErr.Stderr = redact(timeoutErr.Stdout), redact(timeoutErr.Stderr)
+			case errors.As(err, &connectionErr):
+				connectionErr.Stdout, connectionErr.Stderr = redact(connectionErr.Stdout), redact(connectionErr.Stderr)
+			}
+			return result, err
+		}
+	}
+}
+
+// Retry runs the command up to attempts times while retryable returns true for its error, waiting delay between attempts.
+// By default only a ConnectionError of connecting or writing the command is retried, as the command did not run;
+// commands that are not idempotent should not be retried on other errors
+func Retry(attempts int, delay time.Duration, retryable func(err error) bool) Middleware {
+	if retryable == nil {
+		retryable = isNotDelivered
+	}
+	return func(next RunFunc) RunFunc {
+		return func(ctx context.Context, command string, options ...Option) (*Result, error) {
+			for attempt := 1; ; attempt++ {
+				result, err := next(ctx, command, options...)
+				if err == nil || attempt >= attempts || !retryable(err) {
+					return result, err
+				}
+				select {
+				case <-ctx.Done():
+					return result, err
+				case <-time.After(delay):
+				}
+			}
+		}
+	}
+}
+
+// redact returns text with matches of any of the expressions masked
+func redact(text string, expressions []*regexp.Regexp) string {
+	for _, expr := range expressions {
+		text = expr.ReplaceAllString(text, redactedMask)
+	}
+	return text
+}
+
+// isNotDelivered returns true for a ConnectionError of connecting or writing the command, wrapped errors included
+func isNotDelivered(err error) bool {
+	var connectionErr *ConnectionError
+	return errors.As(err, &connectionErr) && (connectionErr.Stream == "" || connectionErr.Stream == Stdin)
+}
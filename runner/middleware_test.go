@@ -0,0 +1,288 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRunner returns queued results and errors, streaming stdout to the chunk listener
+type fakeRunner struct {
+	commands []string
+	results  []*Result
+	errors   []error
+	sent     string
+	closed   bool
+	waiting  bool
+}
+
+func (f *fakeRunner) Run(ctx context.Context, command string, options ...Option) (string, int, error) {
+	f.commands = append(f.commands, command)
+	result, err := f.results[0], f.errors[0]
+	if len(f.results) > 1 {
+		f.results, f.errors = f.results[1:], f.errors[1:]
+	}
+	if listener := (&Options{}).Apply(options).chunkListener; listener != nil && result != nil {
+		listener(Stdout, result.Stdout)
+	}
+	if result == nil {
+		return "", 0, err
+	}
+	return result.Stdout, result.ExitCode, err
+}
+
+func (f *fakeRunner) Send(ctx context.Context, data []byte) (int, error) {
+	f.sent += string(data)
+	return len(data), nil
+}
+
+func (f *fakeRunner) Waiting() bool {
+	return f.waiting
+}
+
+func (f *fakeRunner) PID() int {
+	return 7
+}
+
+func (f *fakeRunner) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestWrap(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeRunner{results: []*Result{{Stdout: "out", ExitCode: 1}}, errors: []error{nil}}
+	var trace []string
+	tracing := func(name string) Middleware {
+		return func(next RunFunc) RunFunc {
+			return func(ctx context.Context, command string, options ...Option) (*Result, error) {
+				trace = append(trace, name+" before")
+				result, err := next(ctx, command+" "+name, options...)
+				trace = append(trace, name+" after")
+				return result, err
+			}
+		}
+	}
+	deny := func(next RunFunc) RunFunc {
+		return func(ctx context.Context, command string, options ...Option) (*Result, error) {
+			if strings.HasPrefix(command, "rm") {
+				return nil, errors.New("denied")
+			}
+			return next(ctx, command, options...)
+		}
+	}
+	wrapped := Wrap(fake, tracing("outer"), tracing("inner"), deny)
+	output, code, err := wrapped.Run(ctx, "ls")
+	assert.Nil(t, err)
+	assert.Equal(t, "out", output)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, []string{"ls outer inner"}, fake.commands)
+	assert.Equal(t, []string{"outer before", "inner before", "inner after", "outer after"}, trace)
+
+	_, _, err = Wrap(fake, deny).Run(ctx, "rm -rf /")
+	assert.EqualError(t, err, "denied")
+	assert.Len(t, fake.commands, 1)
+
+	trace = nil
+	n, err := wrapped.Send(ctx, []byte("secret\n")) // sent data is not passed through the middleware
+	assert.Nil(t, err)
+	assert.Equal(t, "secret\n", fake.sent)
+	assert.Equal(t, len(fake.sent), n)
+	assert.Empty(t, trace)
+	assert.Equal(t, 7, wrapped.PID())
+	assert.Nil(t, wrapped.Close())
+	assert.True(t, fake.closed)
+	assert.Equal(t, ErrResizeNotSupported, Resize(wrapped, 80, 24))
+	assert.Equal(t, fake, Wrap(fake))
+}
+
+func TestWrapSend(t *testing.T) {
+	ctx := context.Background()
+	var checked []string
+	hook := func(ctx context.Context, input string) error {
+		checked = append(checked, input)
+		switch {
+		case strings.Count(input, "'")%2 == 1:
+			return ErrIncomplete
+		case strings.Contains(input, "rm "):
+			return errors.New("denied")
+		}
+		return nil
+	}
+	var testCases = []struct {
+		description string
+		waiting     bool
+		data        []string
+		expectSent  string
+		expectErr   bool
+	}{
+		{description: "line", data: []string{"ls /tmp\n"}, expectSent: "ls /tmp\n"},
+		{description: "denied line", data: []string{"rm /tmp/x\n"}, expectErr: true},
+		{description: "denied split line", data: []string{"r", "m /tmp/x\n"}, expectErr: true},
+		{description: "partial line held", data: []string{"ls\nrm /tm"}, expectSent: "ls\n"},
+		{description: "incomplete script held", data: []string{"echo 'a\n", "'; rm /tmp/x\n"}, expectErr: true},
+		{description: "complete script", data: []string{"echo 'a\n", "b'\n"}, expectSent: "echo 'a\nb'\n"},
+		{description: "read by command", waiting: true, data: []string{"rm /tmp/x\n"}, expectSent: "rm /tmp/x\n"},
+	}
+	for _, testCase := range testCases {
+		fake := &fakeRunner{waiting: testCase.waiting}
+		wrapped := WrapSend(Wrap(fake, Observe(func(command string, elapsed time.Duration, result *Result, err error) {
+			assert.Fail(t, "sent data passed through the middleware", testCase.description)
+		})), hook)
+		var err error
+		for _, data := range testCase.data {
+			if _, err = wrapped.Send(ctx, []byte(data)); err != nil {
+				break
+			}
+		}
+		assert.Equal(t, testCase.expectErr, err != nil, testCase.description)
+		assert.Equal(t, testCase.expectSent, fake.sent, testCase.description)
+	}
+	assert.NotEmpty(t, checked)
+	fake := &fakeRunner{}
+	assert.Equal(t, fake, WrapSend(fake))
+}
+
+// fakeJobRunner starts jobs that finish at once
+type fakeJobRunner struct {
+	fakeRunner
+	started []string
+}
+
+func (f *fakeJobRunner) Start(ctx context.Context, command string, options ...Option) (*Job, error) {
+	f.started = append(f.started, command)
+	job := NewJob(command, nil)
+	job.Finish(0, nil)
+	return job, nil
+}
+
+func TestWrap_Start(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeJobRunner{}
+	var observed []string
+	guard := func(next RunFunc) RunFunc {
+		return func(ctx context.Context, command string, options ...Option) (*Result, error) {
+			if strings.HasPrefix(command, "rm") {
+				return nil, errors.New("denied")
+			}
+			return next(ctx, "nice "+command, options...)
+		}
+	}
+	wrapped := Wrap(fake, Observe(func(command string, elapsed time.Duration, result *Result, err error) {
+		observed = append(observed, command)
+	}), guard)
+	job, err := Start(ctx, wrapped, "make build")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "nice make build", job.Command)
+	}
+	_, err = Start(ctx, wrapped, "rm -rf /")
+	assert.EqualError(t, err, "denied")
+	assert.Equal(t, []string{"nice make build"}, fake.started)
+	assert.Equal(t, []string{"make build", "rm -rf /"}, observed)
+	assert.Empty(t, fake.commands)
+}
+
+func TestObserve(t *testing.T) {
+	fake := &fakeRunner{results: []*Result{{Stdout: "out", ExitCode: 2}, nil}, errors: []error{nil, errors.New("broken")}}
+	var observed []string
+	wrapped := Wrap(fake, Observe(func(command string, elapsed time.Duration, result *Result, err error) {
+		if err != nil {
+			observed = append(observed, command+":"+err.Error())
+			return
+		}
+		observed = append(observed, command+":"+result.Stdout)
+	}))
+	_, _, _ = wrapped.Run(context.Background(), "ls")
+	_, _, _ = wrapped.Run(context.Background(), "pwd")
+	assert.Equal(t, []string{"ls:out", "pwd:broken"}, observed)
+}
+
+func TestLogging(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buffer, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	fake := &fakeRunner{results: []*Result{{}, {ExitCode: 2}, nil}, errors: []error{nil, nil, errors.New("broken")}}
+	wrapped := Wrap(fake, Logging(logger))
+	for _, command := range []string{"ls", "make", "pwd"} {
+		_, _, _ = wrapped.Run(context.Background(), command)
+	}
+	logs := buffer.String()
+	assert.Contains(t, logs, `level=INFO msg="command completed" command=ls`)
+	assert.Contains(t, logs, `level=WARN msg="command exited" command=make`)
+	assert.Contains(t, logs, "exitCode=2")
+	assert.Contains(t, logs, `level=ERROR msg="command failed" command=pwd`)
+	assert.Contains(t, logs, "error=broken")
+}
+
+func TestRedact(t *testing.T) {
+	secret := regexp.MustCompile(`token=\S+`)
+	var testCases = []struct {
+		description string
+		result      *Result
+		err         error
+		expect      string
+		expectErr   string
+	}{
+		{description: "result", result: &Result{Stdout: "auth token=abc ok"}, expect: "auth ****** ok"},
+		{description: "exit error", result: &Result{Stdout: "token=abc", ExitCode: 1}, err: &ExitError{Command: "login", Stdout: "token=abc"}, expect: "******", expectErr: "******"},
+		{description: "timeout error", err: &TimeoutError{Command: "login", Stdout: "token=abc"}, expectErr: "******"},
+	}
+	for _, testCase := range testCases {
+		fake := &fakeRunner{results: []*Result{testCase.result}, errors: []error{testCase.err}}
+		var chunks []string
+		result, err := RunResult(context.Background(), Wrap(fake, Redact(secret)), "login", WithChunkListener(func(stream Stream, chunk string) {
+			chunks = append(chunks, chunk)
+		}))
+		if testCase.result != nil {
+			assert.Equal(t, testCase.expect, result.Stdout, testCase.description)
+			assert.Equal(t, []string{testCase.expect}, chunks, testCase.description)
+		}
+		var exitErr *ExitError
+		var timeoutErr *TimeoutError
+		switch {
+		case errors.As(err, &exitErr):
+			assert.Equal(t, testCase.expectErr, exitErr.Stdout, testCase.description)
+		case errors.As(err, &timeoutErr):
+			assert.Equal(t, testCase.expectErr, timeoutErr.Stdout, testCase.description)
+		default:
+			assert.Nil(t, err, testCase.description)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	notDelivered := &ConnectionError{Stream: Stdin, Cause: errors.New("broken pipe")}
+	interrupted := &ConnectionError{Stream: Stdout, Cause: errors.New("EOF")}
+	var testCases = []struct {
+		description string
+		attempts    int
+		retryable   func(err error) bool
+		errors      []error
+		expectRuns  int
+		expectErr   error
+	}{
+		{description: "success", attempts: 3, errors: []error{nil}, expectRuns: 1},
+		{description: "not delivered", attempts: 3, errors: []error{notDelivered, notDelivered, nil}, expectRuns: 3},
+		{description: "wrapped not delivered", attempts: 3, errors: []error{fmt.Errorf("session: %w", notDelivered), nil}, expectRuns: 2},
+		{description: "attempts exhausted", attempts: 2, errors: []error{notDelivered, notDelivered, nil}, expectRuns: 2, expectErr: notDelivered},
+		{description: "interrupted is not retried", attempts: 3, errors: []error{interrupted, nil}, expectRuns: 1, expectErr: interrupted},
+		{description: "custom retryable", attempts: 3, retryable: func(err error) bool { return errors.Is(err, ErrConnection) }, errors: []error{interrupted, nil}, expectRuns: 2},
+	}
+	for _, testCase := range testCases {
+		results := make([]*Result, len(testCase.errors))
+		for i := range results {
+			results[i] = &Result{}
+		}
+		fake := &fakeRunner{results: results, errors: testCase.errors}
+		_, _, err := Wrap(fake, Retry(testCase.attempts, time.Millisecond, testCase.retryable)).Run(context.Background(), "ls")
+		assert.Equal(t, testCase.expectErr, err, testCase.description)
+		assert.Len(t, fake.commands, testCase.expectRuns, testCase.description)
+	}
+}
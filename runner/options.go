@@ -28,10 +28,16 @@ type (
 		History            *History
 		bufferSize         int
 		listener           Listener
+		chunkListener      ChunkListener
 		timeoutMs          int
 		flashIntervalMs    int
 		terminators        []string
 		pipeline           bool
+		outputLimit        int
+		exitError          bool
+		interactive        bool
+		privilege          *Privilege
+		pty                bool
 	}
 
 	//Option represents runner option
@@ -55,6 +61,11 @@ func (o *Options) AsPipeline() bool {
 	return o.pipeline
 }
 
+// Pty returns true if the shell runs on a pseudo terminal
+func (o *Options) Pty() bool {
+	return o.pty
+}
+
 // Apply applies options
 func (o *Options) Apply(options []Option) *Options {
 	ret := *o
@@ -85,10 +96,10 @@ func NewOptions(opts []Option) *Options {
 	if opt.Term == "" {
 		opt.Term = "xterm"
 	}
-	if opt.Cols > 0 {
+	if opt.Cols <= 0 {
 		opt.Cols = 100
 	}
-	if opt.Rows > 0 {
+	if opt.Rows <= 0 {
 		opt.Rows = 100
 	}
 	return opt
@@ -112,7 +123,7 @@ func WithShell(shell string) Option {
 func WithShellPrompt(shellPrompt string) Option {
 	return func(o *Options) {
 		o.shellPrompt = shellPrompt
-		o.escapedShellPrompt = term.Clean(shellPrompt)
+		o.escapedShellPrompt = term.Normalize(shellPrompt)
 	}
 }
 
@@ -165,6 +176,28 @@ func WithListener(listener Listener) Option {
 	}
 }
 
+// WithChunkListener creates with chunk listener option
+func WithChunkListener(listener ChunkListener) Option {
+	return func(o *Options) {
+		o.chunkListener = listener
+	}
+}
+
+// WithTerm creates with terminal type option
+func WithTerm(term string) Option {
+	return func(o *Options) {
+		o.Term = term
+	}
+}
+
+// WithTermSize creates with terminal size option
+func WithTermSize(cols, rows int) Option {
+	return func(o *Options) {
+		o.Cols = cols
+		o.Rows = rows
+	}
+}
+
 // WithTerminators creates with terminators option
 func WithTerminators(terminators []string) Option {
 	return func(o *Options) {
@@ -172,6 +205,54 @@ func WithTerminators(terminators []string) Option {
 	}
 }
 
+// WithOutputLimit creates with output limit option, output exceeding limit bytes is truncated
+func WithOutputLimit(limit int) Option {
+	return func(o *Options) {
+		o.outputLimit = limit
+	}
+}
+
+// WithExitError creates with exit error option, a non zero exit code is returned as ExitError
+func WithExitError() Option {
+	return func(o *Options) {
+		o.exitError = true
+	}
+}
+
+// WithInteractive creates with interactive option, the command reads the shell stdin, so data written with Send reaches it
+func WithInteractive() Option {
+	return func(o *Options) {
+		o.interactive = true
+	}
+}
+
+// WithPrivilege creates with privilege option, commands run with escalated privileges; nil runs them as the session user
+func WithPrivilege(privilege *Privilege) Option {
+	return func(o *Options) {
+		o.privilege = privilege
+	}
+}
+
+// WithSudo creates with sudo option, commands run as user (root when empty) with the privilege strategy
+// and credential set with WithPrivilege, sudo without password by default
+func WithSudo(user string) Option {
+	return func(o *Options) {
+		privilege := &Privilege{}
+		if o.privilege != nil {
+			*privilege = *o.privilege
+		}
+		privilege.User = user
+		o.privilege = privilege
+	}
+}
+
+// WithPty creates with pty option, the local shell runs on a pseudo terminal sized with Cols and Rows, with Term set as TERM
+func WithPty() Option {
+	return func(o *Options) {
+		o.pty = true
+	}
+}
+
 func AsPipeline() Option {
 	return func(o *Options) {
 		o.pipeline = true
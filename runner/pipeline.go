@@ -3,13 +3,15 @@ package runner
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"os"
 	"runtime"
-	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/viant/gosh/term"
@@ -20,8 +22,15 @@ const (
 	// Slightly higher drain timeout to reduce flakiness when residual output
 	// appears just after issuing a new command.
 	drainTimeoutMs = 100
+	// stderrGraceMs is how long stderr is polled for after the status marker
+	stderrGraceMs = 10
+	// interruptGraceMs is how long the shell is given to resync after each interrupt signal
+	interruptGraceMs = 1000
 )
 
+// interruptSignals are sent in order to the command that has not completed
+var interruptSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, os.Kill}
+
 type (
 	//Pipeline represents a command pipeline
 	Pipeline struct {
@@ -29,20 +38,29 @@ type (
 		bufferSize int
 		mux        sync.Mutex
 		running    int32
+		stdin      io.Writer
 		stdout     io.Reader
 		stderr     io.Reader
 		output     chan string
 		error      chan string
 		options    *Options
 		done       chan bool
+		sentinel   *sentinel
+		workdir    string
+		incomplete bool
+		stopped    bool
+		waiting    bool
 	}
 )
 
 // FormatCmd formats a command that is sent to an interactive shell via stdin.
 //
 // Key guarantees:
-//   - Always append a "status:" marker so the runner can detect completion and
-//     capture the exit code.
+//   - Wrap the command with begin and end markers carrying a per command nonce
+//     (__GOSH_<nonce>__:begin and __GOSH_<nonce>__:<code>), so the runner can
+//     detect completion and capture the exit code without being fooled by
+//     program output or by leftovers of an earlier command. Output that arrives
+//     before the begin marker is discarded.
 //   - Shield the shell's stdin from the user command by grouping and redirecting
 //     that group's stdin to /dev/null. This prevents commands that read from
 //     stdin from consuming the subsequently appended status marker. Explicit
@@ -50,31 +68,70 @@ type (
 //   - Attempt to enable 'pipefail' (if supported) inside the group so pipelines
 //     report a non-zero status when any segment fails. On shells without
 //     pipefail, this attempt is silenced and the status falls back to that of the
-//     last command in the pipeline (standard POSIX behavior).
+//     last command in the pipeline (standard POSIX behavior). The option is
+//     probed in a subshell first, since a failing 'set' is a special builtin
+//     error that makes non-interactive shells such as dash exit.
 //
 // Final layout:
 //
-//	{ set -o pipefail 2>/dev/null; <user_command>; } </dev/null
-//	status=$?; echo 'status:'$status
+//	echo '__GOSH_<nonce>''__:begin'; { (set -o pipefail) 2>/dev/null && set -o pipefail; <user_command>; } </dev/null
+//	status=$?; echo '__GOSH_<nonce>''__:'$status":$PWD"
+//
+// The end marker also reports the shell working directory, so that a runner
+// can restore it after reconnecting.
+//
+// With WithInteractive the command reads the shell stdin, so that data written
+// with Send reaches it; the whole layout is then kept on a single line, since a
+// shell reading a pipe one byte at a time would leave the status line for the
+// command to consume:
+//
+//	echo '__GOSH_<nonce>''__:begin'; { (set -o pipefail) 2>/dev/null && set -o pipefail; <user_command>; }; status=$?; echo '__GOSH_<nonce>''__:'$status":$PWD"
+//
+// Markers are split into two quoted words so that a terminal echoing the
+// command line never produces them verbatim. Using a group redirection avoids
+// brittle parsing (quotes, pipes, heredocs) and reliably shields the shell stdin
+// across a wide range of inputs.
 //
-// Using a group redirection avoids brittle parsing (quotes, pipes, heredocs)
-// and reliably shields the shell stdin across a wide range of inputs.
-func (p *Pipeline) FormatCmd(cmd string) string {
+// With WithSudo or WithPrivilege the user command is first wrapped with the
+// privilege escalation command, see Privilege.Wrap. A sudo ticket is validated
+// before the group, so that the shell reads the password the runner writes to its
+// stdin once the prompt shows up; the password is never part of the command.
+// su and doas prompts are answered only before the escalated command output.
+func (p *Pipeline) FormatCmd(cmd string, opts ...Option) string {
+	options := p.options.Apply(opts)
+	aSentinel := newSentinel()
+	aSentinel.interactive = options.interactive
+	p.mux.Lock()
+	p.sentinel = aSentinel
+	p.waiting = options.interactive
+	p.mux.Unlock()
 	shell := strings.ToLower(p.options.Shell)
 	if runtime.GOOS == "windows" || strings.Contains(shell, "cmd.exe") || strings.Contains(shell, "powershell") || strings.Contains(shell, "pwsh") {
-		return p.formatCmdWindows(cmd)
+		return p.formatCmdWindows(cmd, aSentinel)
+	}
+	validation := ""
+	if options.privilege != nil {
+		validation = options.privilege.validation()
+		cmd = options.privilege.Wrap(cmd)
 	}
-	return p.formatCmdPosix(cmd)
+	return p.formatCmdPosix(cmd, validation, aSentinel, options.interactive)
 }
 
-func (p *Pipeline) formatCmdPosix(cmd string) string {
+func (p *Pipeline) formatCmdPosix(cmd, validation string, aSentinel *sentinel, interactive bool) string {
 	cmd = EnsureLineTermination(cmd)
 	body := strings.TrimSuffix(cmd, "\n")
-	grouped := "{ set -o pipefail 2>/dev/null; " + body + "; } </dev/null\n"
-	return grouped + "status=$?; echo 'status:'$status\n"
+	if validation != "" {
+		validation += " && "
+	}
+	grouped := "echo '" + aSentinel.split("''", beginMarker) + "'; " + validation + "{ (set -o pipefail) 2>/dev/null && set -o pipefail; " + body + "; }"
+	status := "status=$?; echo '" + aSentinel.split("''", "") + "'$status\":$PWD\"\n"
+	if interactive {
+		return grouped + "; " + status
+	}
+	return grouped + " </dev/null\n" + status
 }
 
-func (p *Pipeline) formatCmdWindows(cmd string) string {
+func (p *Pipeline) formatCmdWindows(cmd string, aSentinel *sentinel) string {
 	shell := strings.ToLower(p.options.Shell)
 	if strings.Contains(shell, "powershell") || strings.Contains(shell, "pwsh") {
 		// PowerShell: Use try/catch and $LASTEXITCODE for external commands
@@ -84,16 +141,17 @@ func (p *Pipeline) formatCmdWindows(cmd string) string {
 		body := strings.TrimSuffix(cmd, "\n")
 		// Redirect stdin from $null is not necessary in PowerShell; avoid complexity.
 		// Emit status from $LASTEXITCODE; best-effort for native commands.
-		return body + "; $code = $LASTEXITCODE; Write-Output \"status:$code\"\r\n"
+		begin := "Write-Output ('" + aSentinel.split("' + '", beginMarker) + "'); "
+		return begin + body + "; $code = $LASTEXITCODE; Write-Output ('" + aSentinel.split("' + '", "") + "' + $code)\r\n"
 	}
 	// cmd.exe: group with parentheses, protect stdin with NUL, emit %ERRORLEVEL%
-	// Ensure CRLF to be friendly with cmd.exe
+	// Ensure CRLF to be friendly with cmd.exe, caret escapes split the markers
 	if !strings.HasSuffix(cmd, "\n") {
 		cmd += "\n"
 	}
 	body := strings.TrimSuffix(cmd, "\n")
-	grouped := "(" + body + ") < NUL\r\n"
-	return grouped + "echo status:%ERRORLEVEL%\r\n"
+	grouped := "echo " + aSentinel.split("^", beginMarker) + "\r\n(" + body + ") < NUL\r\n"
+	return grouped + "echo " + aSentinel.split("^", "%ERRORLEVEL%") + "\r\n"
 }
 
 func EnsureLineTermination(cmd string) string {
@@ -116,6 +174,8 @@ func (p *Pipeline) Drain(ctx context.Context, opts ...Option) {
 
 // Err returns error
 func (p *Pipeline) Err() error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
 	return p.err
 }
 
@@ -187,7 +247,11 @@ func (p *Pipeline) copy(reader io.Reader, dest chan string, notification *sync.W
 }
 
 func (p *Pipeline) closeIfError(writeError error) error {
-	p.err = writeError
+	p.mux.Lock()
+	if p.err == nil {
+		p.err = writeError
+	}
+	p.mux.Unlock()
 	return p.Close()
 }
 
@@ -217,6 +281,15 @@ func (p *Pipeline) Listen(ctx context.Context, opts ...Option) error {
 
 // Read reads output
 func (p *Pipeline) Read(ctx context.Context, opts ...Option) (output string, has bool, code int, err error) {
+	result, has, err := p.ReadResult(ctx, opts...)
+	if result == nil {
+		return "", false, 0, err
+	}
+	return result.Output(), has, result.ExitCode, err
+}
+
+// ReadResult reads output keeping stdout and stderr apart
+func (p *Pipeline) ReadResult(ctx context.Context, opts ...Option) (result *Result, has bool, err error) {
 	options := p.options.Apply(opts)
 	timeoutMs := options.timeoutMs
 	var hasPrompt, hasTerminator bool
@@ -225,7 +298,6 @@ func (p *Pipeline) Read(ctx context.Context, opts ...Option) (output string, has
 	var done int32
 	defer atomic.StoreInt32(&done, 1)
 	var errOut string
-	var hasOutput bool
 
 	var waitTimeMs = 0
 	var tickFrequencyMs = defaultTickFrequency
@@ -235,18 +307,68 @@ func (p *Pipeline) Read(ctx context.Context, opts ...Option) (output string, has
 	var timeoutDuration = time.Duration(tickFrequencyMs) * time.Millisecond
 	out := ""
 	var statusCode *int
+	aSentinel := p.takeSentinel()
+	begun := aSentinel == nil || aSentinel.begun
+	var pending string
+	var closed, timedOut bool
+	lastStream := Stdout
+	responded := options.privilege == nil
+	escalated := options.privilege == nil
+	emitted := 0
+	emitStdout := func(end int) {
+		if options.chunkListener == nil || end <= emitted {
+			return
+		}
+		if chunk := p.removePromptIfNeeded(out[emitted:end]); chunk != "" {
+			options.chunkListener(Stdout, chunk)
+		}
+		emitted = end
+	}
 outer:
 	for {
 		select {
 		case partialOutput := <-p.output:
 			waitTimeMs = 0
-			if code := p.extractStatusCode(&partialOutput); code != nil {
-				statusCode = code
+			closed = len(partialOutput) == 0
+			if !closed {
+				lastStream = Stdout
 			}
-
+			if !begun {
+				pending += partialOutput
+				if partialOutput, begun = aSentinel.skipBegin(pending); !begun && !closed {
+					continue
+				}
+				pending = ""
+			}
+			offset := len(out)
 			out += partialOutput
-			if statusCode != nil {
-				break outer
+			if aSentinel != nil {
+				if !escalated {
+					escalated = options.privilege.escalated(&out)
+				}
+				if !responded && p.stdin != nil {
+					var response string
+					if response, responded = options.privilege.respond(out, escalated); responded {
+						out = options.privilege.unprompt(out)
+						if _, writeErr := p.stdin.Write([]byte(response)); writeErr != nil {
+							err = &ConnectionError{Stream: Stdin, Cause: writeErr}
+							break outer
+						}
+					}
+				}
+				if statusCode = aSentinel.extractStatusCode(&out, p.removePromptIfNeeded); statusCode != nil {
+					p.setWorkdir(aSentinel.workdir)
+					emitStdout(len(out))
+					if len(out) > offset {
+						window.notify(p.removePromptIfNeeded(out[offset:]))
+					}
+					break outer
+				}
+				if escalated {
+					emitStdout(aSentinel.safeLength(out))
+				}
+			} else {
+				emitStdout(len(out))
 			}
 
 			hasTerminator = p.hasTerminator(out, options.terminators...)
@@ -256,22 +378,15 @@ outer:
 				}
 				window.notify(p.removePromptIfNeeded(partialOutput))
 			}
-			if hasTerminator || len(partialOutput) == 0 {
-				break outer
-			}
-			if code := p.extractStatusCode(&out); code != nil {
-				statusCode = code
-			}
-			if (hasTerminator || statusCode != nil) && len(p.output) == 0 {
+			if hasTerminator || closed {
 				break outer
 			}
 		case e := <-p.error:
 			errOut += e
+			lastStream = Stderr
 			window.notify(p.removePromptIfNeeded(e))
-			if code := p.extractStatusCode(&out); code != nil {
-				statusCode = code
-			}
-			if (hasTerminator || statusCode != nil) && len(p.error) == 0 {
+			p.notifyStderr(options, e)
+			if hasTerminator && len(p.error) == 0 {
 				break outer
 			}
 			hasTerminator = p.hasTerminator(errOut, options.terminators...)
@@ -279,56 +394,198 @@ outer:
 				break outer
 			}
 		case <-ctx.Done():
-			return "", false, 0, fmt.Errorf("context was cancelled or timed out")
-			// Context was cancelled or timed out
+			err = ctx.Err()
+			break outer
 		case <-time.After(timeoutDuration):
 			waitTimeMs += tickFrequencyMs
 			if waitTimeMs >= timeoutMs {
+				timedOut = true
 				break outer
 			}
 		}
 	}
-	if errOut != "" {
-		out += errOut
+	stopped := aSentinel != nil && statusCode == nil && hasTerminator && !closed && err == nil
+	if stopped { // the command keeps running, the next read awaits its end marker
+		aSentinel.begun = true
+		p.mux.Lock()
+		p.sentinel = aSentinel
+		p.mux.Unlock()
 	}
-
+	p.setState(aSentinel != nil && statusCode == nil && !stopped, stopped, stopped && aSentinel.interactive)
+	if statusCode != nil {
+		errOut += p.pendingStderr(window, options)
+	} else {
+		emitStdout(len(out))
+	}
+	result = &Result{}
 	if len(out) > 0 {
-		hasOutput = true
-		out = p.removePromptIfNeeded(out)
+		has = true
+		result.Stdout = p.removePromptIfNeeded(out)
+	}
+	if len(errOut) > 0 {
+		has = true
+		result.Stderr = p.removePromptIfNeeded(errOut)
 	}
 	if statusCode == nil {
 		statusCode = &defaultCode
 	}
-	return out, hasOutput, *statusCode, err
+	result.ExitCode = *statusCode
+	result.Truncated = truncate(result, options.outputLimit)
+	if aSentinel != nil && !p.Completed() {
+		result.ExitCode = -1
+		switch {
+		case closed:
+			cause := p.Err()
+			if cause == nil {
+				cause = io.EOF
+			}
+			err = &ConnectionError{Stream: Stdout, Stdout: result.Stdout, Stderr: result.Stderr, Cause: cause}
+		case timedOut || errors.Is(err, context.DeadlineExceeded):
+			err = &TimeoutError{Timeout: time.Duration(timeoutMs) * time.Millisecond, Stream: lastStream, Stdout: result.Stdout, Stderr: result.Stderr, Cause: err}
+		}
+	}
+	return result, has, err
+}
+
+// pendingStderr collects stderr written before the status marker but not yet consumed,
+// stdout and stderr are read by separate goroutines, so the marker may overtake it
+func (p *Pipeline) pendingStderr(window *window, options *Options) string {
+	var errOut string
+	for {
+		select {
+		case e := <-p.error:
+			errOut += e
+			window.notify(p.removePromptIfNeeded(e))
+			p.notifyStderr(options, e)
+		case <-time.After(stderrGraceMs * time.Millisecond):
+			return errOut
+		}
+	}
+}
+
+func (p *Pipeline) notifyStderr(options *Options, chunk string) {
+	if options.chunkListener == nil {
+		return
+	}
+	if chunk = p.removePromptIfNeeded(chunk); chunk != "" {
+		options.chunkListener(Stderr, chunk)
+	}
 }
 
-var zeroPos = 0
+// truncate limits combined stdout and stderr to limit bytes, stdout takes precedence
+func truncate(result *Result, limit int) bool {
+	if limit <= 0 || len(result.Stdout)+len(result.Stderr) <= limit {
+		return false
+	}
+	if len(result.Stdout) > limit {
+		result.Stdout = result.Stdout[:limit]
+	}
+	remaining := limit - len(result.Stdout)
+	if len(result.Stderr) > remaining {
+		result.Stderr = result.Stderr[:remaining]
+	}
+	return true
+}
 
-func (p *Pipeline) extractStatusCode(out *string) *int {
-	if index := strings.LastIndex(*out, "\n"); index != -1 {
-		var candidate string
-		var update *int
-		if prevIndex := strings.LastIndex((*out)[:index], "\n"); prevIndex != -1 {
-			candidate = strings.TrimSpace(term.Clean((*out)[prevIndex:index]))
-			index = prevIndex
-			update = &index
+// WorkingDirectory returns the shell working directory reported by the last completed command
+func (p *Pipeline) WorkingDirectory() string {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.workdir
+}
 
-		} else {
-			candidate = strings.TrimSpace(term.Clean((*out)[:index]))
-			update = &zeroPos
+func (p *Pipeline) setWorkdir(workdir string) {
+	if workdir == "" {
+		return
+	}
+	p.mux.Lock()
+	p.workdir = workdir
+	p.mux.Unlock()
+}
+
+// Completed returns false if the last read command did not report its end marker before the timeout or context cancellation,
+// such a command may still be running in the shell
+func (p *Pipeline) Completed() bool {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return !p.incomplete
+}
+
+// Stopped returns true if the last read command stopped at a terminator, the command keeps running,
+// so that data written with Send can answer it, and Await reads it until its end marker
+func (p *Pipeline) Stopped() bool {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.stopped
+}
+
+// Waiting returns true if a command run with WithInteractive was written to the shell and reads its stdin,
+// until it completes or, if it stopped at a terminator, until Await reads it
+func (p *Pipeline) Waiting() bool {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.waiting
+}
+
+func (p *Pipeline) setState(incomplete, stopped, waiting bool) {
+	p.mux.Lock()
+	p.incomplete, p.stopped, p.waiting = incomplete, stopped, waiting
+	p.mux.Unlock()
+}
+
+// Await reads the command that stopped at a terminator until its end marker within the timeout of the options,
+// terminators are ignored; a command that does not complete is left incomplete, see Interrupt
+func (p *Pipeline) Await(ctx context.Context, opts ...Option) {
+	if !p.Stopped() {
+		return
+	}
+	timeoutMs := p.options.Apply(opts).timeoutMs
+	_, _, _ = p.ReadResult(ctx, WithTimeout(timeoutMs), WithTerminators(nil))
+}
+
+// Interrupt stops the command that has not completed and resynchronises the shell.
+// Signals escalate from SIGINT through SIGTERM to SIGKILL, signal delivers them to the foreground command;
+// after the first one a no-op command is written to stdin, the shell runs it once the foreground command is gone
+// and its begin marker discards the late output of the interrupted command, its end marker confirms the resync.
+func (p *Pipeline) Interrupt(stdin io.Writer, signal func(sig os.Signal) error) error {
+	if p.Completed() {
+		return nil
+	}
+	var resync *sentinel
+	var err error
+	for i, sig := range interruptSignals {
+		if signalErr := signal(sig); signalErr != nil {
+			err = signalErr
+		}
+		if i == 0 {
+			if _, writeErr := stdin.Write([]byte(p.FormatCmd(":"))); writeErr != nil {
+				return writeErr
+			}
+			resync = p.takeSentinel()
 		}
-		candidate = p.removePromptIfNeeded(candidate)
-		if !strings.HasPrefix(candidate, "status:") {
+		p.mux.Lock()
+		p.sentinel = resync
+		p.mux.Unlock()
+		if _, _, readErr := p.ReadResult(context.Background(), WithTimeout(interruptGraceMs), WithTerminators(nil)); readErr == nil && p.Completed() {
 			return nil
 		}
-		candidate = candidate[7:]
-		code, err := strconv.Atoi(candidate)
-		if err == nil {
-			*out = (*out)[:*update]
-			return &code
+		if !p.Running() {
+			break
 		}
 	}
-	return nil
+	if err == nil {
+		err = fmt.Errorf("command did not stop")
+	}
+	return fmt.Errorf("failed to resynchronise shell: %w", err)
+}
+
+// takeSentinel returns and clears the sentinel of the last formatted command
+func (p *Pipeline) takeSentinel() *sentinel {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	ret := p.sentinel
+	p.sentinel = nil
+	return ret
 }
 
 func (p *Pipeline) hasPrompt(input string) bool {
@@ -341,7 +598,7 @@ func (p *Pipeline) hasPrompt(input string) bool {
 	if p.options.escapedShellPrompt == "" {
 		return false
 	}
-	escapedInput := term.Clean(input)
+	escapedInput := term.Normalize(input)
 	return strings.HasSuffix(escapedInput, p.options.escapedShellPrompt)
 }
 
@@ -353,10 +610,10 @@ func (p *Pipeline) removePromptIfNeeded(stdout string) string {
 }
 
 func (p *Pipeline) hasTerminator(input string, terminators ...string) bool {
-	if len(p.options.terminators) == 0 {
+	if len(terminators) == 0 {
 		return false
 	}
-	escapedInput := term.Clean(input)
+	escapedInput := term.Normalize(input)
 	input = escapedInput
 	for _, candidate := range terminators {
 		candidateLen := len(candidate)
@@ -415,6 +672,7 @@ func NewPipeline(ctx context.Context, in io.WriteCloser, stdout io.Reader, stder
 	ret := &Pipeline{
 		running: 1,
 		options: options,
+		stdin:   in,
 		stdout:  stdout,
 		stderr:  stderr,
 		output:  make(chan string, 1),
@@ -0,0 +1,205 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/viant/scy/cred"
+)
+
+// Privilege escalation strategies
+const (
+	EscalationSudo = "sudo"
+	EscalationSu   = "su"
+	EscalationDoas = "doas"
+)
+
+var (
+	// ErrPasswordRequired matches escalations that need a password that was not supplied
+	ErrPasswordRequired = errors.New("password is required")
+	// ErrAuthentication matches escalations that failed with the supplied password
+	ErrAuthentication = errors.New("authentication failed")
+	// ErrNotPermitted matches escalations the user has no rights for
+	ErrNotPermitted = errors.New("not permitted")
+	// ErrTerminalRequired matches escalations that read the password from a terminal the runner does not have
+	ErrTerminalRequired = errors.New("terminal is required")
+)
+
+const (
+	// sudoPrompt is printed before the shell reads the password validating the sudo ticket, the runner answers it with the credential password
+	sudoPrompt = "[gosh] sudo password: "
+	// escalatedMarker is printed by su and doas commands once they run with escalated privileges, password prompts are answered only before it
+	escalatedMarker = "[gosh] escalated\n"
+)
+
+// passwordPrompt matches su and doas password prompts written to the terminal
+var passwordPrompt = regexp.MustCompile(`(?i)password[^\n]*:\s*$`)
+
+// privilegeFailures maps escalation messages to errors, messages are matched in order
+var privilegeFailures = map[string][]struct {
+	message string
+	reason  error
+}{
+	EscalationSudo: {
+		{message: "incorrect password attempt", reason: ErrAuthentication},
+		{message: "is not in the sudoers file", reason: ErrNotPermitted},
+		{message: "is not allowed to", reason: ErrNotPermitted},
+		{message: "may not run sudo", reason: ErrNotPermitted},
+		{message: "sudo: a terminal is required", reason: ErrTerminalRequired},
+		{message: "sudo: a password is required", reason: ErrPasswordRequired},
+	},
+	EscalationSu: {
+		{message: "su: must be run from a terminal", reason: ErrTerminalRequired},
+		{message: "su: Authentication failure", reason: ErrAuthentication},
+		{message: "su: Sorry", reason: ErrAuthentication},
+		{message: "su: Permission denied", reason: ErrNotPermitted},
+	},
+	EscalationDoas: {
+		{message: "doas: Authentication failed", reason: ErrAuthentication},
+		{message: "doas: Operation not permitted", reason: ErrNotPermitted},
+		{message: "doas: a password is required", reason: ErrPasswordRequired},
+		{message: "doas: not a terminal", reason: ErrTerminalRequired},
+	},
+}
+
+type (
+	// Privilege represents privilege escalation of commands
+	Privilege struct {
+		// Strategy is one of EscalationSudo (default), EscalationSu or EscalationDoas
+		Strategy string
+		// User commands run as, root when empty
+		User string
+		// Credential password answers the prompt: the session user password for sudo and doas, the target user password for su
+		Credential *cred.Basic
+	}
+
+	// PrivilegeError represents a command that could not be run with escalated privileges
+	PrivilegeError struct {
+		Command  string
+		Host     string
+		Strategy string
+		User     string
+		// Reason is ErrPasswordRequired, ErrAuthentication, ErrNotPermitted or ErrTerminalRequired
+		Reason error
+		Stderr string
+	}
+)
+
+// Error returns error message
+func (e *PrivilegeError) Error() string {
+	return fmt.Sprintf("%v as %v failed on %v for %q: %v", e.Strategy, e.User, e.Host, e.Command, e.Reason)
+}
+
+// Unwrap returns reason
+func (e *PrivilegeError) Unwrap() error {
+	return e.Reason
+}
+
+func (p *Privilege) strategy() string {
+	if p.Strategy == "" {
+		return EscalationSudo
+	}
+	return p.Strategy
+}
+
+func (p *Privilege) user() string {
+	if p.User == "" {
+		return "root"
+	}
+	return p.User
+}
+
+func (p *Privilege) password() (string, bool) {
+	if p.Credential == nil {
+		return "", false
+	}
+	return p.Credential.Password, true
+}
+
+// Wrap returns command running supplied one with escalated privileges, the password is never part of the command.
+// sudo runs non interactively, see validation; su and doas read the password from the terminal, it is written when their prompt
+// shows up before the command prints escalatedMarker
+func (p *Privilege) Wrap(command string) string {
+	user, inner := quote(p.user()), quote(command)
+	switch p.strategy() {
+	case EscalationSu:
+		inner = quote("echo " + quote(strings.TrimSuffix(escalatedMarker, "\n")) + "; " + command)
+		return "su - " + user + " -c " + inner
+	case EscalationDoas:
+		inner = quote("echo " + quote(strings.TrimSuffix(escalatedMarker, "\n")) + "; " + command)
+		if _, ok := p.password(); ok {
+			return "doas -u " + user + " sh -c " + inner
+		}
+		return "doas -n -u " + user + " sh -c " + inner
+	}
+	return "sudo -n -u " + user + " -- sh -c " + inner
+}
+
+// validation returns command validating the sudo ticket once it is not cached, empty without a credential;
+// the shell reads a single password line from its stdin after the prompt, so the command runs before stdin is shielded,
+// and pipes it to sudo -S: a wrong password fails on end of input instead of sudo prompting again and reading the next command
+func (p *Privilege) validation() string {
+	if _, ok := p.password(); !ok || p.strategy() != EscalationSudo {
+		return ""
+	}
+	return "{ sudo -n true 2>/dev/null || { printf '%s' " + quote(sudoPrompt) + "; IFS= read -r gosh_password; " +
+		`printf '%s\n' "$gosh_password" | sudo -S -p '' -v 2>&1 && unset gosh_password || { unset gosh_password; false; }; }; }`
+}
+
+// escalated reports whether output passed the privilege escalation, it removes the escalation output preceding escalatedMarker;
+// sudo output is not preceded by anything
+func (p *Privilege) escalated(output *string) bool {
+	if p.strategy() == EscalationSudo {
+		return true
+	}
+	index := strings.Index(*output, escalatedMarker)
+	if index == -1 {
+		return false
+	}
+	*output = (*output)[index+len(escalatedMarker):]
+	return true
+}
+
+// unprompt removes the sudo prompt, redirected to stdout by validation, from the output answered by respond
+func (p *Privilege) unprompt(output string) string {
+	if p.strategy() != EscalationSudo {
+		return output
+	}
+	return strings.TrimSuffix(output, sudoPrompt)
+}
+
+// respond returns the line answering a password prompt ending the output; su and doas prompts are answered only
+// before escalated output, otherwise the password would be typed into the command own prompt
+func (p *Privilege) respond(output string, escalated bool) (string, bool) {
+	if p.strategy() == EscalationSudo {
+		if !strings.HasSuffix(output, sudoPrompt) {
+			return "", false
+		}
+	} else if escalated || !passwordPrompt.MatchString(output) {
+		return "", false
+	}
+	password, _ := p.password()
+	return password + "\n", true
+}
+
+// failure returns escalation failure reported by a command that exited with non zero code
+func (p *Privilege) failure(result *Result) error {
+	output := result.Stderr + "\n" + result.Stdout
+	for _, candidate := range privilegeFailures[p.strategy()] {
+		if !strings.Contains(output, candidate.message) {
+			continue
+		}
+		if candidate.reason == ErrAuthentication && p.Credential == nil {
+			return ErrPasswordRequired
+		}
+		return candidate.reason
+	}
+	return nil
+}
+
+// quote quotes text as a single POSIX shell word
+func quote(text string) string {
+	return "'" + strings.ReplaceAll(text, "'", `'\''`) + "'"
+}
@@ -0,0 +1,80 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy/cred"
+)
+
+func TestPrivilege_Wrap(t *testing.T) {
+	var testCases = []struct {
+		description string
+		privilege   *Privilege
+		command     string
+		expect      string
+	}{
+		{description: "sudo as root", privilege: &Privilege{}, command: "id -u", expect: `sudo -n -u 'root' -- sh -c 'id -u'`},
+		{
+			description: "sudo with password",
+			privilege:   &Privilege{User: "postgres", Credential: &cred.Basic{Password: "it's"}},
+			command:     "echo 'a'",
+			expect:      `sudo -n -u 'postgres' -- sh -c 'echo '\''a'\'''`,
+		},
+		{description: "su", privilege: &Privilege{Strategy: EscalationSu}, command: "id -u", expect: `su - 'root' -c 'echo '\''[gosh] escalated'\''; id -u'`},
+		{description: "doas", privilege: &Privilege{Strategy: EscalationDoas, User: "www"}, command: "id -u", expect: `doas -n -u 'www' sh -c 'echo '\''[gosh] escalated'\''; id -u'`},
+		{description: "doas with password", privilege: &Privilege{Strategy: EscalationDoas, Credential: &cred.Basic{Password: "secret"}}, command: "id -u", expect: `doas -u 'root' sh -c 'echo '\''[gosh] escalated'\''; id -u'`},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expect, testCase.privilege.Wrap(testCase.command), testCase.description)
+	}
+}
+
+func TestPrivilege_respond(t *testing.T) {
+	var testCases = []struct {
+		description string
+		privilege   *Privilege
+		output      string
+		escalated   bool
+		expect      string
+		responded   bool
+	}{
+		{description: "su prompt", privilege: &Privilege{Strategy: EscalationSu, Credential: &cred.Basic{Password: "secret"}}, output: "Password: ", expect: "secret\n", responded: true},
+		{description: "doas prompt", privilege: &Privilege{Strategy: EscalationDoas, Credential: &cred.Basic{Password: "secret"}}, output: "doas (dev@host) password: ", expect: "secret\n", responded: true},
+		{description: "su prompt without credential", privilege: &Privilege{Strategy: EscalationSu}, output: "Password: ", expect: "\n", responded: true},
+		{description: "no prompt", privilege: &Privilege{Strategy: EscalationSu}, output: "password: reset\n"},
+		{description: "command prompt", privilege: &Privilege{Strategy: EscalationSu, Credential: &cred.Basic{Password: "secret"}}, output: "Enter password: ", escalated: true},
+		{description: "sudo", privilege: &Privilege{Credential: &cred.Basic{Password: "secret"}}, output: "Password: "},
+		{description: "sudo prompt", privilege: &Privilege{Credential: &cred.Basic{Password: "secret"}}, output: sudoPrompt, expect: "secret\n", responded: true},
+	}
+	for _, testCase := range testCases {
+		actual, responded := testCase.privilege.respond(testCase.output, testCase.escalated)
+		assert.Equal(t, testCase.expect, actual, testCase.description)
+		assert.Equal(t, testCase.responded, responded, testCase.description)
+	}
+}
+
+func TestPrivilege_escalated(t *testing.T) {
+	var testCases = []struct {
+		description string
+		privilege   *Privilege
+		output      string
+		expect      string
+		escalated   bool
+	}{
+		{description: "sudo", privilege: &Privilege{}, output: "out", expect: "out", escalated: true},
+		{description: "su prompt", privilege: &Privilege{Strategy: EscalationSu}, output: "Password: ", expect: "Password: "},
+		{description: "su escalated", privilege: &Privilege{Strategy: EscalationSu}, output: "Password: \n" + escalatedMarker + "out", expect: "out", escalated: true},
+		{description: "doas partial marker", privilege: &Privilege{Strategy: EscalationDoas}, output: "[gosh] esc", expect: "[gosh] esc"},
+	}
+	for _, testCase := range testCases {
+		output := testCase.output
+		assert.Equal(t, testCase.escalated, testCase.privilege.escalated(&output), testCase.description)
+		assert.Equal(t, testCase.expect, output, testCase.description)
+	}
+}
+
+func TestPrivilege_unprompt(t *testing.T) {
+	assert.Equal(t, "", (&Privilege{}).unprompt(sudoPrompt))
+	assert.Equal(t, "Password: ", (&Privilege{Strategy: EscalationSu}).unprompt("Password: "))
+}
@@ -0,0 +1,163 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/viant/afs"
+	"github.com/viant/afs/file"
+	"github.com/viant/gosh/runner"
+	"gopkg.in/yaml.v3"
+)
+
+// CassetteVersion represents current cassette format version
+const CassetteVersion = 1
+
+const (
+	// InteractionRun represents Run interaction
+	InteractionRun = "run"
+	// InteractionSend represents Send interaction
+	InteractionSend = "send"
+)
+
+const (
+	// ErrorTimeout represents recorded runner.TimeoutError
+	ErrorTimeout = "timeout"
+	// ErrorConnection represents recorded runner.ConnectionError
+	ErrorConnection = "connection"
+	// ErrorExit represents recorded runner.ExitError
+	ErrorExit = "exit"
+	// ErrorNotFound represents recorded runner.ErrNotFound
+	ErrorNotFound = "notFound"
+)
+
+type (
+	// Cassette represents recorded runner traffic
+	Cassette struct {
+		Version      int            `json:"version" yaml:"version"`
+		PID          int            `json:"pid,omitempty" yaml:"pid,omitempty"`
+		Interactions []*Interaction `json:"interactions" yaml:"interactions"`
+	}
+
+	// Interaction represents a single recorded Run or Send call
+	Interaction struct {
+		Type       string `json:"type" yaml:"type"`
+		Command    string `json:"command,omitempty" yaml:"command,omitempty"`
+		Data       string `json:"data,omitempty" yaml:"data,omitempty"`
+		Stdout     string `json:"stdout,omitempty" yaml:"stdout,omitempty"`
+		Stderr     string `json:"stderr,omitempty" yaml:"stderr,omitempty"`
+		ExitCode   int    `json:"exitCode" yaml:"exitCode"`
+		DurationMs int64  `json:"durationMs,omitempty" yaml:"durationMs,omitempty"`
+		Truncated  bool   `json:"truncated,omitempty" yaml:"truncated,omitempty"`
+		Error      string `json:"error,omitempty" yaml:"error,omitempty"`
+		ErrorKind  string `json:"errorKind,omitempty" yaml:"errorKind,omitempty"`
+	}
+)
+
+// setError records error message and kind
+func (i *Interaction) setError(err error) {
+	i.Error = err.Error()
+	switch {
+	case errors.Is(err, runner.ErrTimeout):
+		i.ErrorKind = ErrorTimeout
+	case errors.Is(err, runner.ErrConnection):
+		i.ErrorKind = ErrorConnection
+	case errors.Is(err, runner.ErrExit):
+		i.ErrorKind = ErrorExit
+	case errors.Is(err, runner.ErrNotFound):
+		i.ErrorKind = ErrorNotFound
+	}
+}
+
+// err returns recorded error, typed by its kind
+func (i *Interaction) err() error {
+	if i.Error == "" {
+		return nil
+	}
+	cause := errors.New(i.Error)
+	switch i.ErrorKind {
+	case ErrorTimeout:
+		return &runner.TimeoutError{Command: i.Command, Host: Host, Stream: runner.Stdout, Stdout: i.Stdout, Stderr: i.Stderr, Cause: cause}
+	case ErrorConnection:
+		return &runner.ConnectionError{Command: i.Command, Host: Host, Stdout: i.Stdout, Stderr: i.Stderr, Cause: cause}
+	case ErrorExit:
+		return &runner.ExitError{Command: i.Command, Host: Host, ExitCode: i.ExitCode, Stdout: i.Stdout, Stderr: i.Stderr}
+	case ErrorNotFound:
+		return fmt.Errorf("%v: %w", i.Error, runner.ErrNotFound)
+	}
+	return cause
+}
+
+// Validate checks if cassette is valid
+func (c *Cassette) Validate() error {
+	if c.Version == 0 || c.Version > CassetteVersion {
+		return fmt.Errorf("unsupported cassette version: %v", c.Version)
+	}
+	for i, interaction := range c.Interactions {
+		switch interaction.Type {
+		case InteractionRun, InteractionSend:
+		default:
+			return fmt.Errorf("invalid interaction[%v] type: %q", i, interaction.Type)
+		}
+	}
+	return nil
+}
+
+// Marshal encodes cassette as YAML when URL has .yaml or .yml extension, JSON otherwise
+func (c *Cassette) Marshal(URL string) ([]byte, error) {
+	if isYAML(URL) {
+		return yaml.Marshal(c)
+	}
+	return json.MarshalIndent(c, "", "  ")
+}
+
+// Save saves cassette to supplied URL
+func (c *Cassette) Save(ctx context.Context, URL string) error {
+	data, err := c.Marshal(URL)
+	if err != nil {
+		return err
+	}
+	return afs.New().Upload(ctx, URL, file.DefaultFileOsMode, bytes.NewReader(data))
+}
+
+// NewCassette creates a new cassette
+func NewCassette(pid int) *Cassette {
+	return &Cassette{Version: CassetteVersion, PID: pid}
+}
+
+// UnmarshalCassette decodes cassette, format is inferred from URL extension
+func UnmarshalCassette(URL string, data []byte) (*Cassette, error) {
+	ret := &Cassette{}
+	var err error
+	if isYAML(URL) {
+		err = yaml.Unmarshal(data, ret)
+	} else {
+		err = json.Unmarshal(data, ret)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode cassette %v: %w", URL, err)
+	}
+	return ret, ret.Validate()
+}
+
+// LoadCassette loads cassette from supplied URL
+func LoadCassette(ctx context.Context, URL string) (*Cassette, error) {
+	data, err := afs.New().DownloadWithURL(ctx, URL)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalCassette(URL, data)
+}
+
+func isYAML(URL string) bool {
+	switch strings.ToLower(path.Ext(URL)) {
+	case ".yaml", ".yml":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package replay
+
+import (
+	"context"
+	"sync"
+
+	"github.com/viant/gosh/runner"
+)
+
+// Recorder represents a runner wrapper recording traffic to a cassette
+type Recorder struct {
+	runner   runner.Runner
+	URL      string
+	mux      sync.Mutex
+	cassette *Cassette
+}
+
+// Run runs supplied command
+func (r *Recorder) Run(ctx context.Context, command string, options ...runner.Option) (string, int, error) {
+	result, err := r.RunResult(ctx, command, options...)
+	if result == nil {
+		return "", 0, err
+	}
+	return result.Output(), result.ExitCode, err
+}
+
+// RunResult runs supplied command and records its result
+func (r *Recorder) RunResult(ctx context.Context, command string, options ...runner.Option) (*runner.Result, error) {
+	result, err := runner.RunResult(ctx, r.runner, command, options...)
+	interaction := &Interaction{Type: InteractionRun, Command: command}
+	if result != nil {
+		interaction.Stdout = result.Stdout
+		interaction.Stderr = result.Stderr
+		interaction.ExitCode = result.ExitCode
+		interaction.DurationMs = result.Duration.Milliseconds()
+		interaction.Truncated = result.Truncated
+	}
+	if err != nil {
+		interaction.setError(err)
+	}
+	r.append(interaction)
+	return result, err
+}
+
+// Send sends data to stdin and records it
+func (r *Recorder) Send(ctx context.Context, data []byte) (int, error) {
+	n, err := r.runner.Send(ctx, data)
+	interaction := &Interaction{Type: InteractionSend, Data: string(data)}
+	if err != nil {
+		interaction.setError(err)
+	}
+	r.append(interaction)
+	return n, err
+}
+
+// PID returns process id
+func (r *Recorder) PID() int {
+	return r.runner.PID()
+}
+
+// Resize resizes the terminal of the underlying runner, the size is not recorded
+func (r *Recorder) Resize(cols, rows int) error {
+	return runner.Resize(r.runner, cols, rows)
+}
+
+// Size returns the terminal size of the underlying runner
+func (r *Recorder) Size() (cols, rows int) {
+	return runner.Size(r.runner)
+}
+
+// Host returns host of the underlying runner
+func (r *Recorder) Host() string {
+	return runner.HostOf(r.runner)
+}
+
+// History returns command history of the underlying runner
+func (r *Recorder) History() *runner.History {
+	return runner.HistoryOf(r.runner)
+}
+
+// Cassette returns a snapshot of recorded cassette
+func (r *Recorder) Cassette() *Cassette {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	ret := *r.cassette
+	ret.PID = r.runner.PID()
+	ret.Interactions = append([]*Interaction{}, r.cassette.Interactions...)
+	return &ret
+}
+
+// Save saves recorded cassette to supplied URL
+func (r *Recorder) Save(ctx context.Context, URL string) error {
+	return r.Cassette().Save(ctx, URL)
+}
+
+// Close closes underlying runner and saves cassette when recorder was created with URL
+func (r *Recorder) Close() error {
+	err := r.runner.Close()
+	if r.URL == "" {
+		return err
+	}
+	if e := r.Save(context.Background(), r.URL); e != nil {
+		return e
+	}
+	return err
+}
+
+func (r *Recorder) append(interaction *Interaction) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+}
+
+// NewRecorder creates a recorder wrapping supplied runner, if URL is not empty cassette is saved on Close
+func NewRecorder(aRunner runner.Runner, URL string) *Recorder {
+	return &Recorder{runner: aRunner, URL: URL, cassette: NewCassette(0)}
+}
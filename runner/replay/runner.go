@@ -3,31 +3,51 @@ package replay
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/viant/gosh/runner"
 )
 
+// Host represents host name reported by replayed errors
+const Host = "replay"
+
+// Mode represents interaction matching mode
+type Mode int
+
+const (
+	// ModeLenient matches any unused interaction with whitespace insensitive command, regardless of order
+	ModeLenient Mode = iota
+	// ModeOrdered matches the first unused interaction after the previous match, skipping unmatched ones
+	ModeOrdered
+	// ModeStrict requires commands and sent data to follow recorded order exactly
+	ModeStrict
+)
+
 // Runner represents a command runner
 type Runner struct {
-	from []*runner.Command
-	pid  int
+	mux          sync.Mutex
+	interactions []*Interaction
+	used         []bool
+	cursor       int
+	mode         Mode
+	pid          int
+	fallback     bool // created by New, commands return empty output once every recorded one was replayed
 }
 
-func (r *Runner) Close() error {
-	return nil
-}
+// Option represents replay runner option
+type Option func(r *Runner)
 
-func (r *Runner) matchRemove(input string) *runner.Command {
-	var matched *runner.Command
-	var from []*runner.Command
-	for i, cmd := range r.from {
-		if cmd.Stdin == input {
-			matched = r.from[i]
-			continue
-		}
-		from = append(from, cmd)
+// WithMode creates with matching mode option
+func WithMode(mode Mode) Option {
+	return func(r *Runner) {
+		r.mode = mode
 	}
-	r.from = from
-	return matched
+}
+
+func (r *Runner) Close() error {
+	return nil
 }
 
 // PID returns process id
@@ -35,19 +55,151 @@ func (r *Runner) PID() int {
 	return r.pid
 }
 
+// Host returns Host
+func (r *Runner) Host() string {
+	return Host
+}
+
 // Run runs supplied command
-func (r *Runner) Run(ctx context.Context, command string, options ...runner.Option) (string, error) {
-	if len(r.from) == 0 {
-		return "", nil
+func (r *Runner) Run(ctx context.Context, command string, options ...runner.Option) (string, int, error) {
+	result, err := r.RunResult(ctx, command, options...)
+	if result == nil {
+		return "", 0, err
+	}
+	return result.Output(), result.ExitCode, err
+}
+
+// RunResult returns recorded result for supplied command
+func (r *Runner) RunResult(ctx context.Context, command string, options ...runner.Option) (*runner.Result, error) {
+	if r.fallback && r.Remaining() == 0 {
+		return &runner.Result{StartedAt: time.Now()}, nil
+	}
+	interaction, err := r.match(InteractionRun, command)
+	if err != nil {
+		return nil, err
 	}
-	cmd := r.matchRemove(command)
-	if cmd == nil {
-		return "", fmt.Errorf("no found")
+	result := &runner.Result{
+		Stdout:    interaction.Stdout,
+		Stderr:    interaction.Stderr,
+		ExitCode:  interaction.ExitCode,
+		StartedAt: time.Now(),
+		Duration:  time.Duration(interaction.DurationMs) * time.Millisecond,
+		Truncated: interaction.Truncated,
 	}
-	return cmd.Output(), cmd.Err()
+	return result, runner.NewOptions(options).Complete(command, Host, result, interaction.err())
 }
 
-// New creates a new runner
+// Send replays recorded send, any data is accepted in lenient mode
+func (r *Runner) Send(ctx context.Context, data []byte) (int, error) {
+	interaction, err := r.match(InteractionSend, string(data))
+	if err != nil {
+		if r.mode == ModeLenient {
+			return len(data), nil
+		}
+		return 0, err
+	}
+	if err = interaction.err(); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Remaining returns number of interactions that have not been replayed
+func (r *Runner) Remaining() int {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	ret := 0
+	for _, used := range r.used {
+		if !used {
+			ret++
+		}
+	}
+	return ret
+}
+
+func (r *Runner) match(kind, input string) (*Interaction, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	index := -1
+	switch r.mode {
+	case ModeStrict:
+		if r.cursor < len(r.interactions) && r.matches(r.interactions[r.cursor], kind, input, false) {
+			index = r.cursor
+		}
+	case ModeOrdered:
+		for i := r.cursor; i < len(r.interactions); i++ {
+			if r.matches(r.interactions[i], kind, input, false) {
+				index = i
+				break
+			}
+		}
+	default:
+		for i, interaction := range r.interactions {
+			if !r.used[i] && r.matches(interaction, kind, input, true) {
+				index = i
+				break
+			}
+		}
+	}
+	if index == -1 {
+		return nil, fmt.Errorf("replay: interaction %w for %v: %q", runner.ErrNotFound, kind, input)
+	}
+	r.used[index] = true
+	r.cursor = index + 1
+	return r.interactions[index], nil
+}
+
+func (r *Runner) matches(interaction *Interaction, kind, input string, normalize bool) bool {
+	if interaction.Type != kind {
+		return false
+	}
+	candidate := interaction.Command
+	if kind == InteractionSend {
+		candidate = interaction.Data
+	}
+	if normalize {
+		return strings.Join(strings.Fields(candidate), " ") == strings.Join(strings.Fields(input), " ")
+	}
+	return candidate == input
+}
+
+// New creates a new runner replaying supplied commands in lenient mode, once they are replayed (or without commands)
+// any command returns empty output; an unmatched command returns runner.ErrNotFound before
 func New(pid int, from []*runner.Command) *Runner {
-	return &Runner{from: from, pid: pid}
+	cassette := NewCassette(pid)
+	for _, cmd := range from {
+		interaction := &Interaction{
+			Type:       InteractionRun,
+			Command:    cmd.Stdin,
+			Stdout:     cmd.Output(),
+			Stderr:     strings.Join(cmd.Stderr, "\n"),
+			ExitCode:   cmd.ExitCode,
+			DurationMs: cmd.Duration.Milliseconds(),
+		}
+		if err := cmd.Err(); err != nil {
+			interaction.Error = err.Error()
+		}
+		cassette.Interactions = append(cassette.Interactions, interaction)
+	}
+	ret := NewFromCassette(cassette)
+	ret.fallback = true
+	return ret
+}
+
+// NewFromCassette creates a new runner replaying supplied cassette
+func NewFromCassette(cassette *Cassette, opts ...Option) *Runner {
+	ret := &Runner{interactions: cassette.Interactions, used: make([]bool, len(cassette.Interactions)), pid: cassette.PID}
+	for _, opt := range opts {
+		opt(ret)
+	}
+	return ret
+}
+
+// Load creates a new runner replaying cassette loaded from supplied URL
+func Load(ctx context.Context, URL string, opts ...Option) (*Runner, error) {
+	cassette, err := LoadCassette(ctx, URL)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromCassette(cassette, opts...), nil
 }
@@ -0,0 +1,153 @@
+package replay
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/gosh"
+	"github.com/viant/gosh/runner"
+	"github.com/viant/gosh/runner/local"
+)
+
+func TestRecordReplay(t *testing.T) {
+	ctx := context.Background()
+	for _, name := range []string{"session.json", "session.yaml"} {
+		URL := filepath.Join(t.TempDir(), name)
+		recorder := NewRecorder(local.New(), URL)
+		srv, err := gosh.New(ctx, recorder)
+		if !assert.Nil(t, err, name) {
+			continue
+		}
+		expect, err := srv.RunResult(ctx, "echo out; echo err 1>&2; false")
+		assert.Nil(t, err, name)
+		assert.Nil(t, srv.Close(), name)
+
+		replayer, err := Load(ctx, URL, WithMode(ModeStrict))
+		if !assert.Nil(t, err, name) {
+			continue
+		}
+		replayed, err := gosh.New(ctx, replayer)
+		if !assert.Nil(t, err, name) {
+			continue
+		}
+		assert.EqualValues(t, srv.OsInfo(), replayed.OsInfo(), name)
+		assert.EqualValues(t, srv.HardwareInfo(), replayed.HardwareInfo(), name)
+		assert.Equal(t, recorder.PID(), replayed.PID(), name)
+		actual, err := replayed.RunResult(ctx, "echo out; echo err 1>&2; false")
+		if !assert.Nil(t, err, name) {
+			continue
+		}
+		assert.Equal(t, expect.Stdout, actual.Stdout, name)
+		assert.Equal(t, expect.Stderr, actual.Stderr, name)
+		assert.Equal(t, 1, actual.ExitCode, name)
+		assert.Equal(t, 0, replayer.Remaining(), name)
+	}
+}
+
+func TestRunner_Match(t *testing.T) {
+	cassette := &Cassette{Version: CassetteVersion, Interactions: []*Interaction{
+		{Type: InteractionRun, Command: "ls", Stdout: "a"},
+		{Type: InteractionSend, Data: "y\n"},
+		{Type: InteractionRun, Command: "pwd", Stdout: "/tmp"},
+	}}
+	var testCases = []struct {
+		description string
+		mode        Mode
+		commands    []string
+		expect      []string
+		hasError    []bool
+	}{
+		{description: "strict in order", mode: ModeStrict, commands: []string{"ls", "pwd"}, expect: []string{"a", ""}, hasError: []bool{false, true}},
+		{description: "strict out of order", mode: ModeStrict, commands: []string{"pwd"}, expect: []string{""}, hasError: []bool{true}},
+		{description: "ordered skip", mode: ModeOrdered, commands: []string{"pwd", "ls"}, expect: []string{"/tmp", ""}, hasError: []bool{false, true}},
+		{description: "lenient any order", mode: ModeLenient, commands: []string{" pwd ", "ls", "ls"}, expect: []string{"/tmp", "a", ""}, hasError: []bool{false, false, true}},
+	}
+	for _, testCase := range testCases {
+		replayer := NewFromCassette(cassette, WithMode(testCase.mode))
+		for i, command := range testCase.commands {
+			output, _, err := replayer.Run(context.Background(), command)
+			assert.Equal(t, testCase.hasError[i], err != nil, testCase.description)
+			assert.Equal(t, testCase.expect[i], output, testCase.description)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	output, code, err := New(1, nil).Run(context.Background(), "uname -s")
+	assert.Nil(t, err)
+	assert.Equal(t, "", output)
+	assert.Equal(t, 0, code)
+
+	replayer := New(1, []*runner.Command{runner.NewCommand("uname -s", "Linux", nil), runner.NewCommand("uname -r", "6.1", nil)})
+	output, _, err = replayer.Run(context.Background(), "uname -s")
+	assert.Nil(t, err)
+	assert.Equal(t, "Linux", output)
+	_, _, err = replayer.Run(context.Background(), "uname -m")
+	assert.ErrorIs(t, err, runner.ErrNotFound)
+	output, _, err = replayer.Run(context.Background(), "uname -r")
+	assert.Nil(t, err)
+	assert.Equal(t, "6.1", output)
+	output, _, err = replayer.Run(context.Background(), "uname -m") // commands ran out
+	assert.Nil(t, err)
+	assert.Equal(t, "", output)
+}
+
+func TestRunner_Events(t *testing.T) {
+	replayer := NewFromCassette(&Cassette{Version: CassetteVersion, Interactions: []*Interaction{
+		{Type: InteractionRun, Command: "make", Stdout: "a\nb", Stderr: "warn\n", ExitCode: 2},
+	}})
+	var types []runner.EventType
+	var data []string
+	for event := range runner.Events(context.Background(), replayer, "make") {
+		types = append(types, event.Type)
+		data = append(data, event.Data)
+	}
+	assert.Equal(t, []runner.EventType{runner.EventStdout, runner.EventLine, runner.EventStderr, runner.EventLine, runner.EventLine, runner.EventExit}, types)
+	assert.Equal(t, []string{"a\nb", "a", "warn\n", "warn", "b", ""}, data)
+
+	var last *runner.Event
+	for event := range runner.Events(context.Background(), replayer, "make") {
+		last = event
+	}
+	if assert.NotNil(t, last) {
+		assert.Equal(t, runner.EventError, last.Type)
+		assert.NotNil(t, last.Err)
+	}
+}
+
+func TestRunner_Start(t *testing.T) {
+	replayer := NewFromCassette(&Cassette{Version: CassetteVersion, Interactions: []*Interaction{
+		{Type: InteractionRun, Command: "make", Stdout: "built", ExitCode: 2},
+	}})
+	job, err := runner.Start(context.Background(), replayer, "make")
+	if !assert.Nil(t, err) {
+		return
+	}
+	result, err := job.Wait(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "built", result.Stdout)
+	assert.Equal(t, 2, result.ExitCode)
+}
+
+func TestRunner_Errors(t *testing.T) {
+	recorder := NewRecorder(local.New(), "")
+	defer recorder.Close()
+	_, _, _ = recorder.Run(context.Background(), "echo started; sleep 30", runner.WithTimeout(300))
+	cassette := recorder.Cassette()
+	assert.Equal(t, ErrorTimeout, cassette.Interactions[0].ErrorKind)
+	cassette.Interactions = append(cassette.Interactions, &Interaction{Type: InteractionRun, Command: "false", ExitCode: 1})
+
+	replayer := NewFromCassette(cassette, WithMode(ModeStrict))
+	_, err := replayer.RunResult(context.Background(), "echo started; sleep 30")
+	timeoutErr := &runner.TimeoutError{}
+	if assert.ErrorAs(t, err, &timeoutErr) {
+		assert.Equal(t, Host, timeoutErr.Host)
+		assert.Equal(t, "started\n", timeoutErr.Stdout)
+	}
+	_, err = replayer.RunResult(context.Background(), "false", runner.WithExitError())
+	assert.ErrorIs(t, err, runner.ErrExit)
+	_, err = replayer.RunResult(context.Background(), "ls")
+	assert.ErrorIs(t, err, runner.ErrNotFound)
+}
@@ -0,0 +1,39 @@
+package runner
+
+import (
+	"context"
+	"time"
+)
+
+// Result represents a command execution result
+type Result struct {
+	Stdout    string
+	Stderr    string
+	ExitCode  int
+	StartedAt time.Time
+	Duration  time.Duration
+	Truncated bool
+}
+
+// Output returns combined stdout and stderr
+func (r *Result) Output() string {
+	return r.Stdout + r.Stderr
+}
+
+// ResultRunner represents a runner that returns a structured result
+type ResultRunner interface {
+	//RunResult runs supplied command and returns its result
+	RunResult(ctx context.Context, command string, options ...Option) (*Result, error)
+}
+
+// RunResult runs supplied command with the runner, falling back to Run when the runner does not implement ResultRunner
+func RunResult(ctx context.Context, runner Runner, command string, options ...Option) (*Result, error) {
+	if resultRunner, ok := runner.(ResultRunner); ok {
+		return resultRunner.RunResult(ctx, command, options...)
+	}
+	result := &Result{StartedAt: time.Now()}
+	var err error
+	result.Stdout, result.ExitCode, err = runner.Run(ctx, command, options...)
+	result.Duration = time.Since(result.StartedAt)
+	return result, err
+}
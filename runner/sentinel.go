@@ -0,0 +1,124 @@
+package runner
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/viant/gosh/term"
+)
+
+const (
+	sentinelPrefix = "__GOSH_"
+	sentinelSuffix = "__"
+	beginMarker    = "begin"
+)
+
+// sentinel represents per command begin/end markers, the nonce makes sure that
+// only markers emitted for the current command are matched
+type sentinel struct {
+	nonce       string
+	marker      string
+	workdir     string
+	begun       bool // begin marker was read, output of a command stopped at a terminator is read again
+	interactive bool // command reads the shell stdin
+}
+
+// split returns marker followed by suffix with the marker broken in two by separator;
+// the shell joins the parts back, while an echoed command line never contains the marker verbatim
+func (s *sentinel) split(separator, suffix string) string {
+	return sentinelPrefix + s.nonce + separator + sentinelSuffix + ":" + suffix
+}
+
+// skipBegin returns output after the begin marker line, ok is false if the marker was not found
+func (s *sentinel) skipBegin(out string) (string, bool) {
+	index := strings.Index(out, s.marker+":"+beginMarker)
+	if index == -1 {
+		return "", false
+	}
+	out = out[index+len(s.marker)+1+len(beginMarker):]
+	if lineEnd := strings.Index(out, "\n"); lineEnd != -1 {
+		return out[lineEnd+1:], true
+	}
+	return strings.TrimPrefix(out, "\r"), true
+}
+
+// extractStatusCode returns exit code from the end marker, output is truncated before the marker;
+// working directory reported after the code (__GOSH_<nonce>__:<code>:<pwd>) is kept in workdir
+func (s *sentinel) extractStatusCode(out *string, removePrompt func(string) string) *int {
+	offset := 0
+	token := s.marker + ":"
+	for {
+		index := strings.Index((*out)[offset:], token)
+		if index == -1 {
+			return nil
+		}
+		index += offset
+		offset = index + len(token)
+		digits := (*out)[offset:]
+		end := 0
+		if end < len(digits) && digits[end] == '-' {
+			end++
+		}
+		for end < len(digits) && digits[end] >= '0' && digits[end] <= '9' {
+			end++
+		}
+		if end == 0 || (end == 1 && digits[0] == '-') {
+			continue
+		}
+		rest := digits[end:]
+		workdir := ""
+		if len(rest) > 0 && rest[0] == ':' {
+			lineEnd := strings.IndexByte(rest, '\n')
+			if lineEnd == -1 {
+				return nil //wait for the whole marker line
+			}
+			workdir = strings.TrimSuffix(rest[1:lineEnd], "\r")
+			rest = rest[lineEnd:]
+		}
+		if len(rest) == 0 || (rest[0] != '\n' && rest[0] != '\r') {
+			return nil //wait for the whole marker line
+		}
+		code, err := strconv.Atoi(digits[:end])
+		if err != nil {
+			continue
+		}
+		lineStart := strings.LastIndex((*out)[:index], "\n") + 1
+		if linePrefix := (*out)[lineStart:index]; isBlank(removePrompt(linePrefix)) {
+			index = lineStart
+		}
+		output := (*out)[:index]
+		output = strings.TrimSuffix(output, "\n")
+		output = strings.TrimSuffix(output, "\r")
+		*out = output
+		s.workdir = workdir
+		return &code
+	}
+}
+
+// safeLength returns length of output that can not be part of the end marker,
+// output starting at the marker or ending with its incomplete prefix is held back
+func (s *sentinel) safeLength(out string) int {
+	if index := strings.Index(out, s.marker); index != -1 {
+		return index
+	}
+	for size := len(s.marker) - 1; size > 0; size-- {
+		if strings.HasSuffix(out, s.marker[:size]) {
+			return len(out) - size
+		}
+	}
+	return len(out)
+}
+
+func newSentinel() *sentinel {
+	nonce := make([]byte, 8)
+	_, _ = rand.Read(nonce)
+	ret := &sentinel{nonce: hex.EncodeToString(nonce)}
+	ret.marker = sentinelPrefix + ret.nonce + sentinelSuffix
+	return ret
+}
+
+func isBlank(text string) bool {
+	return strings.TrimSpace(term.Normalize(text)) == ""
+}
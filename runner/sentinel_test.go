@@ -0,0 +1,65 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSentinel_ExtractStatusCode(t *testing.T) {
+	aSentinel := &sentinel{nonce: "abc", marker: "__GOSH_abc__"}
+	noPrompt := func(s string) string { return s }
+	var testCases = []struct {
+		description string
+		output      string
+		expect      string
+		code        *int
+	}{
+		{description: "end marker", output: "line1\nline2\n__GOSH_abc__:3\n", expect: "line1\nline2", code: intPtr(3)},
+		{description: "negative code", output: "__GOSH_abc__:-1\r\n", expect: "", code: intPtr(-1)},
+		{description: "partial marker line", output: "line1\n__GOSH_abc__:1", expect: "line1\n__GOSH_abc__:1"},
+		{description: "other nonce", output: "__GOSH_xyz__:0\n", expect: "__GOSH_xyz__:0\n"},
+		{description: "echoed command", output: "echo '__GOSH_abc''__:'$status\n", expect: "echo '__GOSH_abc''__:'$status\n"},
+		{description: "working directory", output: "line1\n__GOSH_abc__:0:/tmp/a b\r\n", expect: "line1", code: intPtr(0)},
+		{description: "partial working directory", output: "__GOSH_abc__:0:/tm", expect: "__GOSH_abc__:0:/tm"},
+		{description: "output without line break", output: "abc__GOSH_abc__:0\n", expect: "abc", code: intPtr(0)},
+		{description: "osc title and bracketed paste before marker", output: "line1\n\x1b]0;host: ~\x07\x1b[?2004l\r__GOSH_abc__:0\r\n", expect: "line1", code: intPtr(0)},
+	}
+	for _, testCase := range testCases {
+		output := testCase.output
+		code := aSentinel.extractStatusCode(&output, noPrompt)
+		assert.Equal(t, testCase.code, code, testCase.description)
+		assert.Equal(t, testCase.expect, output, testCase.description)
+	}
+}
+
+func TestSentinel_SkipBegin(t *testing.T) {
+	aSentinel := &sentinel{nonce: "abc", marker: "__GOSH_abc__"}
+	output, ok := aSentinel.skipBegin("leftover\n__GOSH_abc__:begin\r\nfresh")
+	assert.True(t, ok)
+	assert.Equal(t, "fresh", output)
+	_, ok = aSentinel.skipBegin("leftover\n__GOSH_xyz__:begin\n")
+	assert.False(t, ok)
+}
+
+func TestSentinel_SafeLength(t *testing.T) {
+	aSentinel := &sentinel{nonce: "abc", marker: "__GOSH_abc__"}
+	var testCases = []struct {
+		description string
+		output      string
+		expect      int
+	}{
+		{description: "no marker", output: "line1\n", expect: 6},
+		{description: "marker prefix", output: "line1\n__GOS", expect: 6},
+		{description: "whole marker", output: "line1\n__GOSH_abc__:0", expect: 6},
+		{description: "underscore", output: "a_b", expect: 3},
+		{description: "trailing underscore", output: "a_", expect: 1},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expect, aSentinel.safeLength(testCase.output), testCase.description)
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
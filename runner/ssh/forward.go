@@ -0,0 +1,264 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/viant/gosh/runner"
+)
+
+// Forward represents a port forwarding handle
+type Forward struct {
+	listener net.Listener
+	listen   func(addr string) (net.Listener, error) // re-opens remote listener, nil for local forwards
+	dial     func() (net.Conn, error)
+	sent     int64
+	received int64
+	mux      sync.Mutex
+	conns    map[net.Conn]bool
+	closed   bool
+	err      error
+	onClose  func(f *Forward)
+	wg       sync.WaitGroup
+}
+
+// Addr returns listening address
+func (f *Forward) Addr() net.Addr {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.listener.Addr()
+}
+
+// Err returns the error that closed the forward when it could not be re-opened after reconnect
+func (f *Forward) Err() error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.err
+}
+
+// Sent returns number of bytes sent from accepting side to dialed side
+func (f *Forward) Sent() int64 {
+	return atomic.LoadInt64(&f.sent)
+}
+
+// Received returns number of bytes received from dialed side
+func (f *Forward) Received() int64 {
+	return atomic.LoadInt64(&f.received)
+}
+
+// Close stops listening and closes active connections
+func (f *Forward) Close() error {
+	f.mux.Lock()
+	if f.closed {
+		f.mux.Unlock()
+		return nil
+	}
+	f.closed = true
+	err := f.listener.Close()
+	for conn := range f.conns {
+		_ = conn.Close()
+	}
+	f.mux.Unlock()
+	f.wg.Wait()
+	if f.onClose != nil {
+		f.onClose(f)
+	}
+	return err
+}
+
+func (f *Forward) serve(listener net.Listener) {
+	defer f.wg.Done()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		f.wg.Add(1)
+		go f.handle(conn)
+	}
+}
+
+func (f *Forward) handle(conn net.Conn) {
+	defer f.wg.Done()
+	target, err := f.dial()
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	if !f.track(conn, target) {
+		_ = conn.Close()
+		_ = target.Close()
+		return
+	}
+	defer f.untrack(conn, target)
+	done := make(chan struct{})
+	go func() {
+		f.copy(target, conn, &f.sent)
+		close(done)
+	}()
+	f.copy(conn, target, &f.received)
+	<-done
+}
+
+func (f *Forward) copy(dest, source net.Conn, counter *int64) {
+	buf := make([]byte, transferBufferSize)
+	for {
+		n, err := source.Read(buf)
+		if n > 0 {
+			written, writeErr := dest.Write(buf[:n])
+			atomic.AddInt64(counter, int64(written))
+			if writeErr != nil {
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	closeWrite(dest)
+}
+
+// reopen listens again on the bound remote address of a restored connection, the forward is closed if it can not
+func (f *Forward) reopen() error {
+	if f.listen == nil { // local listener outlives the connection, connections dial the current client
+		return nil
+	}
+	addr := f.Addr().String()
+	listener, err := f.listen(addr)
+	f.mux.Lock()
+	if f.closed {
+		f.mux.Unlock()
+		if listener != nil {
+			_ = listener.Close()
+		}
+		return nil
+	}
+	if err != nil {
+		f.err = fmt.Errorf("failed to re-open forward on remote %v: %w", addr, err)
+		f.mux.Unlock()
+		_ = f.Close()
+		return f.err
+	}
+	f.listener = listener
+	f.wg.Add(1)
+	f.mux.Unlock()
+	go f.serve(listener)
+	return nil
+}
+
+func (f *Forward) track(conns ...net.Conn) bool {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.closed {
+		return false
+	}
+	for _, conn := range conns {
+		f.conns[conn] = true
+	}
+	return true
+}
+
+func (f *Forward) untrack(conns ...net.Conn) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	for _, conn := range conns {
+		_ = conn.Close()
+		delete(f.conns, conn)
+	}
+}
+
+// closeWrite half closes connection if supported, closes it otherwise
+func closeWrite(conn net.Conn) {
+	if closer, ok := conn.(interface{ CloseWrite() error }); ok {
+		_ = closer.CloseWrite()
+		return
+	}
+	_ = conn.Close()
+}
+
+// ForwardLocal listens on local address and forwards connections to remote address through the ssh connection,
+// connections accepted after reconnect use the restored connection
+func (r *Runner) ForwardLocal(ctx context.Context, localAddr, remoteAddr string) (*Forward, error) {
+	if err := r.initIfNeeded(ctx); err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", localAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %v: %w", localAddr, err)
+	}
+	return r.forward(listener, nil, func() (net.Conn, error) {
+		r.mux.Lock()
+		client := r.client
+		r.mux.Unlock()
+		if client == nil {
+			return nil, &runner.ConnectionError{Host: r.host, Cause: net.ErrClosed}
+		}
+		return client.Dial("tcp", remoteAddr)
+	}), nil
+}
+
+// ForwardRemote listens on remote address and forwards connections to local address,
+// the remote listener is re-opened on the same address after reconnect, see Forward.Err
+func (r *Runner) ForwardRemote(ctx context.Context, remoteAddr, localAddr string) (*Forward, error) {
+	if err := r.initIfNeeded(ctx); err != nil {
+		return nil, err
+	}
+	listener, err := r.client.Listen("tcp", remoteAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on remote %v: %w", remoteAddr, err)
+	}
+	return r.forward(listener, func(addr string) (net.Listener, error) {
+		return r.client.Listen("tcp", addr)
+	}, func() (net.Conn, error) {
+		return net.Dial("tcp", localAddr)
+	}), nil
+}
+
+func (r *Runner) forward(listener net.Listener, listen func(addr string) (net.Listener, error), dial func() (net.Conn, error)) *Forward {
+	ret := &Forward{listener: listener, listen: listen, dial: dial, conns: map[net.Conn]bool{}, onClose: r.removeForward}
+	r.mux.Lock()
+	r.forwards = append(r.forwards, ret)
+	r.mux.Unlock()
+	ret.wg.Add(1)
+	go ret.serve(listener)
+	return ret
+}
+
+// reopenForwards re-opens remote forwards on a restored connection, forwards that can not be re-opened are closed
+func (r *Runner) reopenForwards() error {
+	r.mux.Lock()
+	forwards := append([]*Forward(nil), r.forwards...)
+	r.mux.Unlock()
+	var errs []error
+	for _, forward := range forwards {
+		if err := forward.reopen(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Runner) removeForward(forward *Forward) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	for i, candidate := range r.forwards {
+		if candidate == forward {
+			r.forwards = append(r.forwards[:i], r.forwards[i+1:]...)
+			return
+		}
+	}
+}
+
+func (r *Runner) closeForwards() {
+	r.mux.Lock()
+	forwards := r.forwards
+	r.forwards = nil
+	r.mux.Unlock()
+	for _, forward := range forwards {
+		_ = forward.Close()
+	}
+}
@@ -0,0 +1,87 @@
+package ssh
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newEchoServer starts a tcp server echoing back received lines
+func newEchoServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					if _, err := conn.Write([]byte("echo:" + scanner.Text() + "\n")); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func roundTrip(t *testing.T, addr string, message string) string {
+	conn, err := net.Dial("tcp", addr)
+	if !assert.Nil(t, err) {
+		return ""
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(message + "\n"))
+	assert.Nil(t, err)
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	assert.Nil(t, err)
+	return line
+}
+
+func TestRunner_Forward(t *testing.T) {
+	ctx := context.Background()
+	server, config := newTestServer(t)
+	echoAddr := newEchoServer(t)
+	aRunner := New(server.Addr(), config)
+
+	local, err := aRunner.ForwardLocal(ctx, "127.0.0.1:0", echoAddr)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "echo:local\n", roundTrip(t, local.Addr().String(), "local"))
+
+	remote, err := aRunner.ForwardRemote(ctx, "127.0.0.1:0", echoAddr)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "echo:remote\n", roundTrip(t, remote.Addr().String(), "remote"))
+
+	assert.Nil(t, local.Close())
+	assert.EqualValues(t, len("local\n"), local.Sent())
+	assert.EqualValues(t, len("echo:local\n"), local.Received())
+	_, err = net.Dial("tcp", local.Addr().String())
+	assert.NotNil(t, err)
+	assert.Len(t, aRunner.forwards, 1)
+
+	assert.Nil(t, aRunner.Close())
+	assert.Len(t, aRunner.forwards, 0)
+	assert.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", remote.Addr().String())
+		if err == nil {
+			_ = conn.Close()
+		}
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+}
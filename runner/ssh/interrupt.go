@@ -0,0 +1,52 @@
+package ssh
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/viant/gosh/runner"
+)
+
+// signalScript signals the foreground process group of the shell terminal (kill -- -<pgid>) when the shell runs
+// commands with job control, otherwise every descendant of the shell, so that pipelines and grandchildren are reached
+const signalScript = `fg=$(ps -o tpgid= -p $PID 2>/dev/null | tr -d ' '); sg=$(ps -o pgid= -p $PID | tr -d ' ')
+if [ "${fg:-0}" -gt 0 ] && [ "$fg" != "$sg" ]; then kill -$SIG -- -$fg; exit; fi
+pids=$(ps -A -o pid= -o ppid= | awk -v root=$PID '{parent[$1]=$2} END {for (p in parent) {q=p; while ((q in parent) && q != root && q > 1) q=parent[q]; if (q == root && p != root) print p}}')
+[ -z "$pids" ] || kill -$SIG $pids`
+
+// interrupt stops the command that did not complete, the session is restarted if the shell can not be resynchronised
+func (r *Runner) interrupt() {
+	if err := r.pipeline.Interrupt(r.stdin, r.signal); err != nil {
+		_ = r.disconnect()
+		atomic.StoreUint32(&r.inited, 0)
+	}
+}
+
+// signal delivers signal to the foreground command: SIGINT is also sent as ^C through the terminal, SIGINT and SIGTERM
+// with a signal request to servers that support it (an interactive shell ignores both, SIGKILL would stop the shell too),
+// and every signal to the process group of the command with kill run on a separate session,
+// which works on servers that ignore signal requests or did not allocate a terminal
+func (r *Runner) signal(sig os.Signal) error {
+	name, ok := signals[sig]
+	if !ok {
+		return runner.ErrSignalNotSupported
+	}
+	if sig == os.Interrupt {
+		if _, err := r.stdin.Write([]byte("\x03\n")); err != nil {
+			return err
+		}
+	}
+	if sig != os.Kill {
+		_ = r.session.Signal(name) // not every server honours signal requests, kill below reaches the command anyway
+	}
+	session, err := r.client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	script := strings.NewReplacer("$PID", strconv.Itoa(r.pid), "$SIG", string(name)).Replace(signalScript)
+	_ = session.Run(script) // exit code is not zero when no process matched
+	return nil
+}
@@ -0,0 +1,90 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+
+	"github.com/viant/gosh/runner"
+	"golang.org/x/crypto/ssh"
+)
+
+// signals maps process signals to ssh signal names
+var signals = map[os.Signal]ssh.Signal{
+	syscall.SIGABRT: ssh.SIGABRT,
+	syscall.SIGALRM: ssh.SIGALRM,
+	syscall.SIGFPE:  ssh.SIGFPE,
+	syscall.SIGHUP:  ssh.SIGHUP,
+	syscall.SIGILL:  ssh.SIGILL,
+	syscall.SIGINT:  ssh.SIGINT,
+	syscall.SIGKILL: ssh.SIGKILL,
+	syscall.SIGPIPE: ssh.SIGPIPE,
+	syscall.SIGQUIT: ssh.SIGQUIT,
+	syscall.SIGSEGV: ssh.SIGSEGV,
+	syscall.SIGTERM: ssh.SIGTERM,
+}
+
+// Start starts supplied command on a separate ssh session in the shell working directory,
+// the runner session stays usable while the job runs.
+// Signals are delivered with ssh signal requests, which not every server honours; Kill also closes the job session.
+// The job is killed when ctx is done before it finishes
+func (r *Runner) Start(ctx context.Context, command string, options ...runner.Option) (*runner.Job, error) {
+	if err := r.initIfNeeded(ctx); err != nil {
+		return nil, err
+	}
+	session, err := r.client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	var exports string
+	for k, v := range r.options.Env {
+		if err = session.Setenv(k, v); err != nil {
+			exports += "export " + k + "=" + quote(v) + "; "
+		}
+	}
+	job := runner.NewJob(command, func(sig os.Signal) error {
+		name, ok := signals[sig]
+		if !ok {
+			return runner.ErrSignalNotSupported
+		}
+		err := session.Signal(name)
+		if sig == os.Kill {
+			err = session.Close()
+		}
+		return err
+	})
+	session.Stdout = job.Writer(runner.Stdout)
+	session.Stderr = job.Writer(runner.Stderr)
+	remote := exports + command
+	if workdir := r.pipeline.WorkingDirectory(); workdir != "" {
+		remote = "cd " + quote(workdir) + " && " + remote
+	} else if r.options.Path != "" {
+		remote = "cd " + quote(r.options.Path) + " && " + remote
+	}
+	if err = session.Start(remote); err != nil {
+		_ = session.Close()
+		return nil, err
+	}
+	go func() {
+		err := session.Wait()
+		_ = session.Close()
+		var exitErr *ssh.ExitError
+		switch {
+		case err == nil:
+			job.Finish(0, nil)
+		case errors.As(err, &exitErr):
+			job.Finish(exitErr.ExitStatus(), nil)
+		default:
+			job.Finish(-1, err)
+		}
+	}()
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = job.Kill()
+		case <-job.Done():
+		}
+	}()
+	return job, nil
+}
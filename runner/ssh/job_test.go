@@ -0,0 +1,50 @@
+package ssh
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/gosh/runner"
+)
+
+func TestRunner_Start(t *testing.T) {
+	ctx := context.Background()
+	server, config := newTestServer(t)
+	aRunner := New(server.Addr(), config, runner.WithEnvironment(map[string]string{"GOSH_JOB": "job"}))
+	defer aRunner.Close()
+	_, _, err := aRunner.Run(ctx, "cd /tmp")
+	if !assert.Nil(t, err) {
+		return
+	}
+	job, err := aRunner.Start(ctx, "pwd; echo $GOSH_JOB; sleep 0.3; echo err 1>&2; exit 3")
+	if !assert.Nil(t, err) {
+		return
+	}
+	output, _, err := aRunner.Run(ctx, "echo session")
+	assert.Nil(t, err)
+	assert.Equal(t, "session", output)
+	_, ok := job.ExitCode()
+	assert.False(t, ok)
+
+	result, err := job.Wait(ctx)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Equal(t, "/tmp\njob\n", result.Stdout)
+	assert.Equal(t, "err\n", result.Stderr)
+
+	job, err = aRunner.Start(ctx, "sleep 10")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, job.Kill())
+	waitCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	_, err = job.Wait(waitCtx)
+	assert.NotEqual(t, context.DeadlineExceeded, err)
+	_, ok = job.ExitCode()
+	assert.True(t, ok)
+}
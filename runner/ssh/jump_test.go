@@ -0,0 +1,51 @@
+package ssh
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner_WithJumpHosts(t *testing.T) {
+	bastion, bastionConfig := newTestServer(t)
+	inner, innerConfig := newTestServer(t)
+	target, targetConfig := newTestServer(t)
+
+	aRunner := New(target.Addr(), targetConfig).WithJumpHosts(
+		&Hop{Host: bastion.Addr(), Config: bastionConfig},
+		&Hop{Host: inner.Addr(), Config: innerConfig},
+	)
+	output, code, err := aRunner.Run(context.Background(), "echo hello")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "hello", strings.TrimSpace(output))
+	if !assert.Len(t, aRunner.tunnel, 2) {
+		return
+	}
+	tunnel := aRunner.tunnel
+	for _, server := range []*testServer{bastion, inner, target} {
+		assert.EqualValues(t, 1, atomic.LoadInt32(&server.connections))
+	}
+	assert.Nil(t, aRunner.Close())
+	assert.Nil(t, aRunner.tunnel)
+	for _, client := range tunnel {
+		_, err = client.NewSession()
+		assert.NotNil(t, err)
+	}
+}
+
+func TestRunner_WithJumpHosts_Error(t *testing.T) {
+	target, targetConfig := newTestServer(t)
+	bastion, bastionConfig := newTestServer(t)
+	bastionConfig.User = "unknown"
+	aRunner := New(target.Addr(), targetConfig).WithJumpHosts(&Hop{Host: bastion.Addr(), Config: bastionConfig})
+	_, _, err := aRunner.Run(context.Background(), "echo hello")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "jump host")
+	assert.EqualValues(t, 0, atomic.LoadInt32(&target.connections))
+}
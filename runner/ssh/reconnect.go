@@ -0,0 +1,171 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/viant/gosh/runner"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	keepAliveRequest      = "keepalive@openssh.com"
+	defaultMaxMissed      = 3
+	defaultReconnectDelay = 500 * time.Millisecond
+	defaultMaxBackoff     = 30 * time.Second
+)
+
+// ErrInterrupted indicates that a command was interrupted by a connection loss
+var ErrInterrupted = errors.New("command interrupted by connection loss")
+
+var errRestoring = errors.New("connection lost while restoring session")
+
+type (
+	// KeepAlive represents keepalive settings
+	KeepAlive struct {
+		// Interval between keepalive requests
+		Interval time.Duration
+		// MaxMissed is number of unanswered requests after which the transport is considered dead
+		MaxMissed int
+	}
+
+	// Reconnect represents reconnect settings
+	Reconnect struct {
+		// MaxAttempts limits reconnect attempts, unlimited when zero
+		MaxAttempts int
+		// Backoff is delay before the second attempt, doubled after each failure
+		Backoff time.Duration
+		// MaxBackoff caps the delay between attempts
+		MaxBackoff time.Duration
+	}
+
+	// InterruptedError represents a command interrupted by a connection loss
+	InterruptedError struct {
+		Command string
+		// Reconnected is true if the session was restored and can be used again
+		Reconnected bool
+		Cause       error
+	}
+)
+
+func (e *InterruptedError) Error() string {
+	state := "session was not restored"
+	if e.Reconnected {
+		state = "session was restored"
+	}
+	return fmt.Sprintf("%v: %q, %v: %v", ErrInterrupted, e.Command, state, e.Cause)
+}
+
+// Is returns true for ErrInterrupted and runner.ErrConnection
+func (e *InterruptedError) Is(target error) bool {
+	return target == ErrInterrupted || target == runner.ErrConnection
+}
+
+// Unwrap returns interruption cause
+func (e *InterruptedError) Unwrap() error {
+	return e.Cause
+}
+
+// WithKeepAlive enables keepalive requests, the connection is closed when maxMissed consecutive requests fail;
+// it has to be called before the first Run
+func (r *Runner) WithKeepAlive(interval time.Duration, maxMissed int) *Runner {
+	if maxMissed <= 0 {
+		maxMissed = defaultMaxMissed
+	}
+	r.keepAlive = &KeepAlive{Interval: interval, MaxMissed: maxMissed}
+	return r
+}
+
+// WithReconnect enables transparent reconnect; working directory, environment, system paths, prompt and forwards are restored
+func (r *Runner) WithReconnect(reconnect *Reconnect) *Runner {
+	if reconnect == nil {
+		reconnect = &Reconnect{}
+	}
+	if reconnect.Backoff == 0 {
+		reconnect.Backoff = defaultReconnectDelay
+	}
+	if reconnect.MaxBackoff == 0 {
+		reconnect.MaxBackoff = defaultMaxBackoff
+	}
+	r.reconnect = reconnect
+	return r
+}
+
+// startKeepAlive sends keepalive requests until done is closed, closing client once the transport is found dead
+func (r *Runner) startKeepAlive(client *ssh.Client, done chan struct{}) {
+	if r.keepAlive == nil || r.keepAlive.Interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(r.keepAlive.Interval)
+		defer ticker.Stop()
+		missed := 0
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			if sendKeepAlive(client, r.keepAlive.Interval) {
+				missed = 0
+				continue
+			}
+			if missed++; missed >= r.keepAlive.MaxMissed {
+				_ = client.Close()
+				return
+			}
+		}
+	}()
+}
+
+func sendKeepAlive(client *ssh.Client, timeout time.Duration) bool {
+	replied := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest(keepAliveRequest, true, nil)
+		replied <- err
+	}()
+	select {
+	case err := <-replied:
+		return err == nil
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// restore reconnects with backoff and restores session state and forwards; commands run while the session
+// is being restored do not restore it again, the connection error is returned to the reconnect loop instead
+func (r *Runner) restore(ctx context.Context) (err error) {
+	if !atomic.CompareAndSwapInt32(&r.restoring, 0, 1) {
+		return &runner.ConnectionError{Host: r.host, Cause: errRestoring}
+	}
+	defer atomic.StoreInt32(&r.restoring, 0)
+	r.disconnect()
+	backoff := r.reconnect.Backoff
+	for attempt := 1; ; attempt++ {
+		if err = r.init(ctx); err == nil {
+			break
+		}
+		r.disconnect()
+		if r.reconnect.MaxAttempts > 0 && attempt >= r.reconnect.MaxAttempts {
+			return fmt.Errorf("failed to reconnect: %v after %v attempts, %w", r.host, attempt, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > r.reconnect.MaxBackoff {
+			backoff = r.reconnect.MaxBackoff
+		}
+	}
+	return r.reopenForwards()
+}
+
+// quote quotes text as a single POSIX shell word
+func quote(text string) string {
+	return "'" + strings.ReplaceAll(text, "'", `'\''`) + "'"
+}
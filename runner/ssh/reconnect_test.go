@@ -0,0 +1,128 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/gosh/runner"
+)
+
+func TestRunner_Reconnect(t *testing.T) {
+	ctx := context.Background()
+	server, config := newTestServer(t)
+	history := runner.NewHistory()
+	aRunner := New(server.Addr(), config, runner.WithEnvironment(map[string]string{"GOSH_TEST": "restored"}), runner.WithHistory(history)).WithReconnect(&Reconnect{Backoff: 10 * time.Millisecond})
+	defer aRunner.Close()
+	_, _, err := aRunner.Run(ctx, "cd /tmp")
+	if !assert.Nil(t, err) {
+		return
+	}
+	pid := aRunner.PID()
+
+	server.DropConnections()
+	time.Sleep(300 * time.Millisecond)
+	output, code, err := aRunner.Run(ctx, "pwd; echo $GOSH_TEST")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "/tmp\nrestored", strings.TrimSpace(output))
+	assert.NotEqual(t, pid, aRunner.PID())
+	assert.EqualValues(t, 2, atomic.LoadInt32(&server.connections))
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		server.DropConnections()
+	}()
+	_, _, err = aRunner.Run(ctx, "sleep 3")
+	assert.True(t, errors.Is(err, ErrInterrupted), err)
+	interrupted := &InterruptedError{}
+	if assert.True(t, errors.As(err, &interrupted)) {
+		assert.True(t, interrupted.Reconnected)
+		assert.Equal(t, "sleep 3", interrupted.Command)
+	}
+	output, _, err = aRunner.Run(ctx, "pwd")
+	assert.Nil(t, err)
+	assert.Equal(t, "/tmp", strings.TrimSpace(output))
+
+	var commands []string // session setup and restore commands are not recorded
+	for _, entry := range history.Entries() {
+		commands = append(commands, entry.Stdin)
+	}
+	assert.Equal(t, []string{"cd /tmp", "pwd; echo $GOSH_TEST", "sleep 3", "pwd"}, commands)
+}
+
+func TestRunner_KeepAlive(t *testing.T) {
+	ctx := context.Background()
+	server, config := newTestServer(t)
+	aRunner := New(server.Addr(), config).WithKeepAlive(50*time.Millisecond, 2)
+	defer aRunner.Close()
+	_, _, err := aRunner.Run(ctx, "echo 1")
+	if !assert.Nil(t, err) {
+		return
+	}
+	time.Sleep(200 * time.Millisecond)
+	assert.True(t, aRunner.pipeline.Running())
+
+	atomic.StoreInt32(&server.silent, 1)
+	time.Sleep(500 * time.Millisecond)
+	assert.False(t, aRunner.pipeline.Running())
+	_, _, err = aRunner.Run(ctx, "echo 1")
+	assert.NotNil(t, err)
+}
+
+func TestRunner_ReconnectForwards(t *testing.T) {
+	ctx := context.Background()
+	server, config := newTestServer(t)
+	echoAddr := newEchoServer(t)
+	aRunner := New(server.Addr(), config).WithReconnect(&Reconnect{Backoff: 10 * time.Millisecond})
+	defer aRunner.Close()
+	local, err := aRunner.ForwardLocal(ctx, "127.0.0.1:0", echoAddr)
+	if !assert.Nil(t, err) {
+		return
+	}
+	remote, err := aRunner.ForwardRemote(ctx, "127.0.0.1:0", echoAddr)
+	if !assert.Nil(t, err) {
+		return
+	}
+	remoteAddr := remote.Addr().String()
+
+	server.DropConnections()
+	time.Sleep(300 * time.Millisecond)
+	_, _, err = aRunner.Run(ctx, "echo restored")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "echo:local\n", roundTrip(t, local.Addr().String(), "local"))
+	assert.Equal(t, remoteAddr, remote.Addr().String())
+	assert.Equal(t, "echo:remote\n", roundTrip(t, remoteAddr, "remote"))
+	assert.Nil(t, remote.Err())
+	assert.Len(t, aRunner.forwards, 2)
+}
+
+func TestRunner_RestoreWorkdir(t *testing.T) {
+	ctx := context.Background()
+	server, config := newTestServer(t)
+	aRunner := New(server.Addr(), config).WithReconnect(&Reconnect{Backoff: 10 * time.Millisecond})
+	defer aRunner.Close()
+	_, _, err := aRunner.Run(ctx, "cd /tmp")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	atomic.StoreInt32(&aRunner.restoring, 1) // restore started by a command run while restoring
+	err = aRunner.restore(ctx)
+	assert.True(t, errors.Is(err, runner.ErrConnection), err)
+	atomic.StoreInt32(&aRunner.restoring, 0)
+
+	_ = aRunner.disconnect() // session restarted after the shell could not be resynchronised by interrupt
+	atomic.StoreUint32(&aRunner.inited, 0)
+	output, _, err := aRunner.Run(ctx, "pwd")
+	assert.Nil(t, err)
+	assert.Equal(t, "/tmp", strings.TrimSpace(output))
+}
@@ -8,13 +8,22 @@ import (
 	"io"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 )
 
+// Hop represents an intermediate host (bastion) the connection is tunneled through
+type Hop struct {
+	Host   string
+	Config *ssh.ClientConfig
+}
+
 // Runner represents ssh runner
 type Runner struct {
 	inited   uint32
+	hops     []*Hop
+	tunnel   []*ssh.Client
 	client   *ssh.Client
 	session  *ssh.Session
 	host     string
@@ -24,6 +33,17 @@ type Runner struct {
 	stdin    io.WriteCloser
 	pid      int
 	counter  int32
+	mux      sync.Mutex
+	cols     int // terminal size guarded by mux, options are read by the pipeline without it
+	rows     int
+	sftp     *transfer
+	forwards []*Forward
+	// restoring is 1 while restore reconnects
+	restoring int32
+
+	keepAlive *KeepAlive
+	reconnect *Reconnect
+	done      chan struct{}
 }
 
 // Send returns stdin writer
@@ -41,31 +61,103 @@ func (r *Runner) ensureCommandStarted() {
 	}
 }
 func (r *Runner) connect() (err error) {
-	if r.client, err = ssh.Dial("tcp", r.host, r.config); err != nil {
-		return fmt.Errorf("failed to dial: %v, %w", r.host, err)
+	var via *ssh.Client
+	for _, hop := range r.hops {
+		if via, err = dial(via, hop.Host, hop.Config); err != nil {
+			r.closeTunnel()
+			return &runner.ConnectionError{Host: hop.Host, Cause: fmt.Errorf("failed to dial jump host: %w", err)}
+		}
+		r.tunnel = append(r.tunnel, via)
 	}
+	client, err := dial(via, r.host, r.config)
+	if err != nil {
+		r.closeTunnel()
+		return &runner.ConnectionError{Host: r.host, Cause: err}
+	}
+	r.mux.Lock()
+	r.client = client
+	r.mux.Unlock()
+	r.done = make(chan struct{})
+	r.startKeepAlive(r.client, r.done)
 	return err
 }
 
+// dial connects to host directly or through via client when supplied
+func dial(via *ssh.Client, host string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	if via == nil {
+		return ssh.Dial("tcp", host, config)
+	}
+	conn, err := via.Dial("tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	clientConn, channels, requests, err := ssh.NewClientConn(conn, host, config)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(clientConn, channels, requests), nil
+}
+
+// closeTunnel closes jump host clients, the closest to target first
+func (r *Runner) closeTunnel() {
+	for i := len(r.tunnel) - 1; i >= 0; i-- {
+		_ = r.tunnel[i].Close()
+	}
+	r.tunnel = nil
+}
+
+// WithJumpHosts sets hosts the connection is tunneled through in order, like ssh ProxyJump; it has to be called before the first Run
+func (r *Runner) WithJumpHosts(hops ...*Hop) *Runner {
+	r.hops = hops
+	return r
+}
+
 func (r *Runner) Close() (err error) {
+	r.closeForwards()
+	return r.disconnect()
+}
+
+// disconnect closes sftp, session, client and tunnel, the closed pipeline is kept to report its state;
+// forwards are kept to be re-opened once the connection is restored
+func (r *Runner) disconnect() (err error) {
+	r.mux.Lock()
+	if r.sftp != nil {
+		_ = r.sftp.Close()
+		r.sftp = nil
+	}
+	r.mux.Unlock()
+	if r.done != nil {
+		close(r.done)
+		r.done = nil
+	}
 	if r.pipeline != nil {
 		_ = r.pipeline.Close()
 	}
 	if r.session != nil {
 		_ = r.session.Close()
+		r.session = nil
 	}
-	if r.client != nil {
-		err = r.client.Close()
+	r.mux.Lock()
+	client := r.client
+	r.client = nil
+	r.mux.Unlock()
+	if client != nil {
+		err = client.Close()
 	}
+	r.closeTunnel()
 	return err
 }
 
 func (r *Runner) start(ctx context.Context) (err error) {
-	r.session, err = r.client.NewSession()
+	if r.session, err = r.client.NewSession(); err != nil {
+		return err
+	}
+	var exports []string
 	for k, v := range r.options.Env {
-		err = r.session.Setenv(k, v)
-		if err != nil {
-			return nil
+		if err = r.session.Setenv(k, v); err != nil {
+			// server does not accept the variable (sshd AcceptEnv), export it in the shell instead
+			exports = append(exports, k+"="+quote(v))
 		}
 	}
 	modes := ssh.TerminalModes{
@@ -74,7 +166,8 @@ func (r *Runner) start(ctx context.Context) (err error) {
 		ssh.TTY_OP_OSPEED: 14400, // output speed = 14.4kbaud
 	}
 
-	if err := r.session.RequestPty(r.options.Term, r.options.Rows, r.options.Cols, modes); err != nil {
+	cols, rows := r.Size()
+	if err := r.session.RequestPty(r.options.Term, rows, cols, modes); err != nil {
 		return err
 	}
 	if r.stdin, err = r.session.StdinPipe(); err != nil {
@@ -91,26 +184,64 @@ func (r *Runner) start(ctx context.Context) (err error) {
 	if err = r.session.Start(r.options.Shell); err != nil {
 		return err
 	}
+	workdir := r.options.Path // the closed pipeline of a lost session reports the directory to restore
+	if r.pipeline != nil && r.pipeline.WorkingDirectory() != "" {
+		workdir = r.pipeline.WorkingDirectory()
+	}
 	r.pipeline, err = runner.NewPipeline(ctx, r.stdin, outPipe, errPipe, r.options)
 	if err != nil {
 		return err
 	}
+	// written through the pipeline, setup commands are not recorded in history
 	var pid string
-	pid, _, err = r.Run(ctx, "echo $$")
+	pid, err = r.setup(ctx, "echo $$")
 	if err == nil {
 		pid = strings.TrimSpace(pid)
 		r.pid, err = strconv.Atoi(pid)
 	}
+	if len(exports) > 0 {
+		_, err = r.setup(ctx, "export "+strings.Join(exports, " "))
+	}
 	if r.options.Path != "" {
-		_, _, err = r.Run(ctx, "cd "+r.options.Path)
+		_, err = r.setup(ctx, "cd "+r.options.Path)
 	}
 	if len(r.options.SystemPaths) > 0 {
-		_, _, err = r.Run(ctx, "export PATH=$PATH:"+strings.Join(r.options.SystemPaths, ":"))
+		_, err = r.setup(ctx, "export PATH=$PATH:"+strings.Join(r.options.SystemPaths, ":"))
+	}
+	if workdir != r.options.Path {
+		_, err = r.setup(ctx, "cd "+quote(workdir))
 	}
 
 	return err
 }
 
+// setup runs session setup command and returns its output
+func (r *Runner) setup(ctx context.Context, command string) (string, error) {
+	if err := r.runCommand(command, nil); err != nil {
+		return "", err
+	}
+	result, _, err := r.pipeline.ReadResult(ctx)
+	if result == nil {
+		return "", err
+	}
+	return result.Output(), err
+}
+
+// Waiting returns true if a command run with runner.WithInteractive is running and reads the shell stdin
+func (r *Runner) Waiting() bool {
+	return atomic.LoadUint32(&r.inited) == 1 && r.pipeline != nil && r.pipeline.Waiting()
+}
+
+// Host returns address of the target host
+func (r *Runner) Host() string {
+	return r.host
+}
+
+// History returns command history set with runner.WithHistory
+func (r *Runner) History() *runner.History {
+	return r.options.History
+}
+
 // PID returns process id
 func (r *Runner) PID() int {
 	return r.pid
@@ -123,7 +254,7 @@ func (r *Runner) init(ctx context.Context) (err error) {
 	}
 	defer func() {
 		if err != nil {
-			r.client.Close()
+			r.disconnect()
 		}
 	}()
 	err = r.start(ctx)
@@ -132,11 +263,32 @@ func (r *Runner) init(ctx context.Context) (err error) {
 
 // Run runs supplied command
 func (r *Runner) Run(ctx context.Context, command string, options ...runner.Option) (string, int, error) {
-	if err := r.initIfNeeded(ctx); err != nil {
+	result, err := r.RunResult(ctx, command, options...)
+	if result == nil {
 		return "", 0, err
 	}
+	return result.Output(), result.ExitCode, err
+}
+
+// RunResult runs supplied command and returns its result
+func (r *Runner) RunResult(ctx context.Context, command string, options ...runner.Option) (*runner.Result, error) {
+	if err := r.initIfNeeded(ctx); err != nil {
+		return nil, err
+	}
 	if !r.pipeline.Running() {
-		return "", 0, r.pipeline.Err()
+		if r.reconnect == nil {
+			return nil, &runner.ConnectionError{Command: command, Host: r.host, Cause: r.pipeline.Err()}
+		}
+		if err := r.restore(ctx); err != nil {
+			return nil, err
+		}
+	}
+	r.pipeline.Await(ctx, options...)
+	if r.pipeline.Running() && !r.pipeline.Completed() { // command stopped at a terminator did not complete
+		r.interrupt()
+		if err := r.initIfNeeded(ctx); err != nil {
+			return nil, err
+		}
 	}
 	r.pipeline.Drain(ctx)
 
@@ -144,34 +296,63 @@ func (r *Runner) Run(ctx context.Context, command string, options ...runner.Opti
 		return r.runAsPipeline(ctx, command, options)
 	}
 
-	err := r.runCommand(command)
+	startedAt := time.Now()
+	err := r.runCommand(command, options)
 	atomic.AddInt32(&r.counter, 1)
 	if err != nil {
-		return "", 0, err
+		return nil, r.interrupted(ctx, command, err)
+	}
+	result, _, err := r.pipeline.ReadResult(ctx, options...)
+	if result != nil {
+		result.StartedAt = startedAt
+		result.Duration = time.Since(startedAt)
+	}
+	if r.pipeline.Running() && !r.pipeline.Completed() {
+		r.interrupt()
+	}
+	err = r.options.Apply(options).Complete(command, r.host, result, err)
+	if !r.pipeline.Running() {
+		err = r.interrupted(ctx, command, err)
 	}
-	output, _, code, err := r.pipeline.Read(ctx, options...)
 	if r.options.History != nil {
-		r.options.History.Commands = append(r.options.History.Commands, runner.NewCommand(command, output, err))
+		entry := runner.NewResultCommand(command, result, err)
+		entry.Host, entry.Dir = r.host, r.pipeline.WorkingDirectory()
+		_ = r.options.History.Append(entry) // store errors are reported by History.Err
 	}
-	return output, code, err
+	return result, err
 }
 
-func (r *Runner) runAsPipeline(ctx context.Context, command string, options []runner.Option) (string, int, error) {
+// interrupted restores the session if reconnect is enabled and returns InterruptedError, otherwise err
+func (r *Runner) interrupted(ctx context.Context, command string, err error) error {
+	if r.reconnect == nil {
+		return err
+	}
+	cause := r.pipeline.Err()
+	if cause == nil {
+		cause = err
+	}
+	restoreErr := r.restore(ctx)
+	return &InterruptedError{Command: command, Reconnected: restoreErr == nil, Cause: cause}
+}
+
+func (r *Runner) runAsPipeline(ctx context.Context, command string, options []runner.Option) (*runner.Result, error) {
+	result := &runner.Result{ExitCode: -1, StartedAt: time.Now()}
 	cmd := runner.EnsureLineTermination(command)
 	_, err := r.stdin.Write([]byte(cmd))
 	atomic.AddInt32(&r.counter, 1)
 	if err != nil {
-		return "", -1, err
+		return result, err
 	}
 	err = r.pipeline.Listen(ctx, append(options, runner.WithFlashIntervalMs(0))...)
-	return "", -1, err
+	result.Duration = time.Since(result.StartedAt)
+	return result, err
 }
 
-func (r *Runner) runCommand(command string) error {
-	var cmd = r.pipeline.FormatCmd(command)
+func (r *Runner) runCommand(command string, options []runner.Option) error {
+	var cmd = r.pipeline.FormatCmd(command, options...)
 	_, err := r.stdin.Write([]byte(cmd))
 	if err != nil {
-		return fmt.Errorf("failed to execute command: %v, err: %v", command, err)
+		return &runner.ConnectionError{Command: command, Host: r.host, Stream: runner.Stdin, Cause: err}
 	}
 	return nil
 }
@@ -181,9 +362,10 @@ func (r *Runner) initIfNeeded(ctx context.Context) error {
 		return nil
 	}
 	if err := r.init(ctx); err != nil {
+		atomic.StoreUint32(&r.inited, 0)
 		return err
 	}
-	return nil
+	return r.reopenForwards() // the session was restarted after an interrupt failure
 }
 
 // New creates a new runner
@@ -194,5 +376,6 @@ func New(host string, config *ssh.ClientConfig, opts ...runner.Option) *Runner {
 		config:  config,
 		options: runner.NewOptions(opts),
 	}
+	ret.cols, ret.rows = ret.options.Cols, ret.options.Rows
 	return ret
 }
@@ -6,9 +6,11 @@ import (
 	"os"
 	"path"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/viant/afs"
+	"github.com/viant/gosh/runner"
 	"github.com/viant/scy/cred"
 )
 
@@ -53,3 +55,70 @@ func getKeyLocation() []byte {
 	keyBytes, _ := fs.DownloadWithURL(ctx, keyLocation)
 	return keyBytes
 }
+
+func TestRunner_Events(t *testing.T) {
+	server, config := newTestServer(t)
+	aRunner := New(server.Addr(), config)
+	defer aRunner.Close()
+	var stdout, stderr string
+	var lines []string
+	var last *runner.Event
+	for event := range runner.Events(context.Background(), aRunner, "echo one; echo err 1>&2; printf two; false") {
+		switch event.Type {
+		case runner.EventStdout:
+			stdout += event.Data
+		case runner.EventStderr:
+			stderr += event.Data
+		case runner.EventLine:
+			lines = append(lines, string(event.Stream)+":"+event.Data)
+		}
+		last = event
+	}
+	assert.Equal(t, "one\ntwo", stdout)
+	assert.Equal(t, "err\n", stderr)
+	assert.ElementsMatch(t, []string{"stdout:one", "stdout:two", "stderr:err"}, lines)
+	if assert.NotNil(t, last) && assert.Equal(t, runner.EventExit, last.Type) {
+		assert.Equal(t, 1, last.ExitCode)
+	}
+}
+
+func TestRunner_Interrupt(t *testing.T) {
+	server, config := newTestServer(t)
+	aRunner := New(server.Addr(), config)
+	defer aRunner.Close()
+	var testCases = []struct {
+		description string
+		command     string
+	}{
+		{description: "timeout", command: "echo started; sleep 30; echo late"},
+		{description: "SIGINT ignored", command: "(trap '' INT; exec sleep 30); echo late"},
+		{description: "grandchild", command: "sh -c 'sleep 37; :' | cat; echo late"},
+	}
+	for _, testCase := range testCases {
+		startedAt := time.Now()
+		_, err := aRunner.RunResult(context.Background(), testCase.command, runner.WithTimeout(300))
+		timeoutErr := &runner.TimeoutError{}
+		if assert.ErrorAs(t, err, &timeoutErr, testCase.description) {
+			assert.Equal(t, server.Addr(), timeoutErr.Host, testCase.description)
+		}
+		assert.True(t, time.Since(startedAt) < 5*time.Second, testCase.description)
+		output, code, err := aRunner.Run(context.Background(), "echo next")
+		assert.Nil(t, err, testCase.description)
+		assert.Equal(t, 0, code, testCase.description)
+		assert.Equal(t, "next", output, testCase.description)
+	}
+	output, _, err := aRunner.Run(context.Background(), "pgrep -f 'sleep 3[7]' || echo stopped")
+	assert.Nil(t, err)
+	assert.Equal(t, "stopped", output)
+}
+
+func TestRunner_ConnectionError(t *testing.T) {
+	server, config := newTestServer(t)
+	addr := server.Addr()
+	_ = server.Close()
+	_, _, err := New(addr, config).Run(context.Background(), "echo hello")
+	connectionErr := &runner.ConnectionError{}
+	if assert.ErrorAs(t, err, &connectionErr) {
+		assert.Equal(t, addr, connectionErr.Host)
+	}
+}
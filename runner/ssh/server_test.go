@@ -0,0 +1,294 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"net"
+	"os/exec"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	testUser     = "gosh"
+	testPassword = "secret"
+)
+
+// testServer represents in-process ssh server supporting shell sessions and direct-tcpip forwarding
+type testServer struct {
+	listener    net.Listener
+	config      *ssh.ServerConfig
+	connections int32
+	silent      int32
+	mux         sync.Mutex
+	conns       []net.Conn
+	sizes       []string // terminal sizes requested with pty-req and window-change, as colsxrows
+}
+
+// Sizes returns requested terminal sizes
+func (s *testServer) Sizes() []string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return append([]string{}, s.sizes...)
+}
+
+func (s *testServer) recordSize(request *ssh.Request) {
+	size := struct{ Cols, Rows, Width, Height uint32 }{}
+	payload := request.Payload
+	if request.Type == "pty-req" {
+		pty := struct {
+			Term                      string
+			Cols, Rows, Width, Height uint32
+			Modes                     string
+		}{}
+		_ = ssh.Unmarshal(payload, &pty)
+		size.Cols, size.Rows = pty.Cols, pty.Rows
+	} else {
+		_ = ssh.Unmarshal(payload, &size)
+	}
+	s.mux.Lock()
+	s.sizes = append(s.sizes, strconv.Itoa(int(size.Cols))+"x"+strconv.Itoa(int(size.Rows)))
+	s.mux.Unlock()
+	if request.WantReply {
+		_ = request.Reply(true, nil)
+	}
+}
+
+func (s *testServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// DropConnections closes all accepted connections
+func (s *testServer) DropConnections() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *testServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		atomic.AddInt32(&s.connections, 1)
+		s.mux.Lock()
+		s.conns = append(s.conns, conn)
+		s.mux.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *testServer) handle(conn net.Conn) {
+	serverConn, channels, requests, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	defer serverConn.Close()
+	go func() {
+		for request := range requests {
+			if request.Type == "tcpip-forward" {
+				s.tcpipForward(serverConn, request)
+				continue
+			}
+			if request.WantReply && atomic.LoadInt32(&s.silent) == 0 {
+				_ = request.Reply(true, nil)
+			}
+		}
+	}()
+	for newChannel := range channels {
+		switch newChannel.ChannelType() {
+		case "session":
+			go s.session(newChannel)
+		case "direct-tcpip":
+			go s.directTCPIP(newChannel)
+		default:
+			_ = newChannel.Reject(ssh.UnknownChannelType, newChannel.ChannelType())
+		}
+	}
+}
+
+func (s *testServer) tcpipForward(serverConn *ssh.ServerConn, request *ssh.Request) {
+	payload := struct {
+		Addr string
+		Port uint32
+	}{}
+	if err := ssh.Unmarshal(request.Payload, &payload); err != nil {
+		_ = request.Reply(false, nil)
+		return
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(payload.Addr, strconv.Itoa(int(payload.Port))))
+	if err != nil {
+		_ = request.Reply(false, nil)
+		return
+	}
+	port := uint32(listener.Addr().(*net.TCPAddr).Port)
+	_ = request.Reply(true, ssh.Marshal(struct{ Port uint32 }{port}))
+	go func() {
+		_ = serverConn.Wait()
+		_ = listener.Close()
+	}()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				origin := conn.RemoteAddr().(*net.TCPAddr)
+				channel, requests, err := serverConn.OpenChannel("forwarded-tcpip", ssh.Marshal(struct {
+					Addr       string
+					Port       uint32
+					OriginAddr string
+					OriginPort uint32
+				}{payload.Addr, port, origin.IP.String(), uint32(origin.Port)}))
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(requests)
+				go func() {
+					_, _ = io.Copy(channel, conn)
+					_ = channel.CloseWrite()
+				}()
+				_, _ = io.Copy(conn, channel)
+				_ = channel.Close()
+			}()
+		}
+	}()
+}
+
+func (s *testServer) directTCPIP(newChannel ssh.NewChannel) {
+	payload := struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}{}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+		_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	target, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+	if err != nil {
+		_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		_ = target.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	go func() {
+		_, _ = io.Copy(target, channel)
+		_ = target.Close()
+	}()
+	_, _ = io.Copy(channel, target)
+	_ = channel.Close()
+}
+
+func (s *testServer) session(newChannel ssh.NewChannel) {
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+	var env []string
+	for request := range requests {
+		switch request.Type {
+		case "env":
+			pair := struct{ Name, Value string }{}
+			_ = ssh.Unmarshal(request.Payload, &pair)
+			env = append(env, pair.Name+"="+pair.Value)
+			_ = request.Reply(true, nil)
+		case "shell", "exec":
+			command := "/bin/sh"
+			if request.Type == "exec" && len(request.Payload) > 4 {
+				command = string(request.Payload[4:])
+			}
+			_ = request.Reply(true, nil)
+			go func() {
+				for request := range requests {
+					if request.Type == "window-change" {
+						s.recordSize(request)
+					} else if request.WantReply {
+						_ = request.Reply(false, nil)
+					}
+				}
+			}()
+			cmd := exec.Command("/bin/sh")
+			if request.Type == "exec" {
+				cmd = exec.Command("/bin/sh", "-c", command)
+			}
+			cmd.Env = env
+			cmd.Stdin = channel
+			cmd.Stdout = channel
+			cmd.Stderr = channel.Stderr()
+			status := uint32(0)
+			if err := cmd.Run(); err != nil {
+				status = 1
+				if exitErr, ok := err.(*exec.ExitError); ok {
+					status = uint32(exitErr.ExitCode())
+				}
+			}
+			statusPayload := make([]byte, 4)
+			binary.BigEndian.PutUint32(statusPayload, status)
+			_, _ = channel.SendRequest("exit-status", false, statusPayload)
+			return
+		case "pty-req":
+			s.recordSize(request)
+		default:
+			if request.WantReply {
+				_ = request.Reply(true, nil)
+			}
+		}
+	}
+}
+
+func (s *testServer) Close() error {
+	s.DropConnections()
+	return s.listener.Close()
+}
+
+func newTestServer(t *testing.T) (*testServer, *ssh.ClientConfig) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == testUser && string(password) == testPassword {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+	}
+	config.AddHostKey(signer)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &testServer{listener: listener, config: config}
+	go server.serve()
+	t.Cleanup(func() { _ = server.Close() })
+	return server, &ssh.ClientConfig{
+		User:            testUser,
+		Auth:            []ssh.AuthMethod{ssh.Password(testPassword)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+}
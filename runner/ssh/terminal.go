@@ -0,0 +1,25 @@
+package ssh
+
+import "fmt"
+
+// Resize changes the session terminal size, it is also used by sessions opened after reconnecting
+func (r *Runner) Resize(cols, rows int) error {
+	if cols <= 0 || rows <= 0 {
+		return fmt.Errorf("invalid terminal size: %vx%v", cols, rows)
+	}
+	r.mux.Lock()
+	r.cols, r.rows = cols, rows
+	session := r.session
+	r.mux.Unlock()
+	if session == nil {
+		return nil
+	}
+	return session.WindowChange(rows, cols)
+}
+
+// Size returns the session terminal size
+func (r *Runner) Size() (cols, rows int) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return r.cols, r.rows
+}
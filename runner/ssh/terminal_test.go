@@ -0,0 +1,30 @@
+package ssh
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/gosh/runner"
+)
+
+func TestRunner_Resize(t *testing.T) {
+	ctx := context.Background()
+	server, config := newTestServer(t)
+	aRunner := New(server.Addr(), config, runner.WithTermSize(80, 24))
+	defer aRunner.Close()
+	_, _, err := aRunner.Run(ctx, "echo started")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, runner.Resize(aRunner, 120, 40))
+	cols, rows := aRunner.Size()
+	assert.Equal(t, 120, cols)
+	assert.Equal(t, 40, rows)
+	assert.NotNil(t, aRunner.Resize(0, 40))
+	assert.Eventually(t, func() bool {
+		return len(server.Sizes()) == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"80x24", "120x40"}, server.Sizes())
+}
@@ -0,0 +1,321 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+const transferBufferSize = 32 * 1024
+
+type (
+	// Progress represents transfer progress listener, total is -1 when unknown
+	Progress func(path string, transferred, total int64)
+
+	transferOptions struct {
+		progress Progress
+		mode     os.FileMode
+		modTime  time.Time
+	}
+
+	// TransferOption represents file transfer option
+	TransferOption func(o *transferOptions)
+
+	// transfer copies files over sftp
+	transfer struct {
+		client *sftp.Client
+	}
+)
+
+// WithProgress creates with progress listener option
+func WithProgress(progress Progress) TransferOption {
+	return func(o *transferOptions) {
+		o.progress = progress
+	}
+}
+
+// WithModTime creates with modification time option
+func WithModTime(modTime time.Time) TransferOption {
+	return func(o *transferOptions) {
+		o.modTime = modTime
+	}
+}
+
+func newTransferOptions(opts []TransferOption) *transferOptions {
+	ret := &transferOptions{}
+	for _, opt := range opts {
+		opt(ret)
+	}
+	return ret
+}
+
+// Upload uploads reader content to remote path with supplied mode
+func (r *Runner) Upload(ctx context.Context, reader io.Reader, remotePath string, mode os.FileMode, opts ...TransferOption) error {
+	aTransfer, err := r.transfer(ctx)
+	if err != nil {
+		return err
+	}
+	options := newTransferOptions(opts)
+	options.mode = mode
+	return aTransfer.upload(ctx, reader, -1, remotePath, options)
+}
+
+// UploadFile uploads local file to remote path preserving permissions and modification time
+func (r *Runner) UploadFile(ctx context.Context, localPath, remotePath string, opts ...TransferOption) error {
+	aTransfer, err := r.transfer(ctx)
+	if err != nil {
+		return err
+	}
+	return aTransfer.uploadFile(ctx, localPath, remotePath, newTransferOptions(opts))
+}
+
+// UploadDir recursively uploads local directory to remote path
+func (r *Runner) UploadDir(ctx context.Context, localDir, remoteDir string, opts ...TransferOption) error {
+	aTransfer, err := r.transfer(ctx)
+	if err != nil {
+		return err
+	}
+	return aTransfer.uploadDir(ctx, localDir, remoteDir, newTransferOptions(opts))
+}
+
+// Download downloads remote file to writer
+func (r *Runner) Download(ctx context.Context, remotePath string, writer io.Writer, opts ...TransferOption) error {
+	aTransfer, err := r.transfer(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = aTransfer.download(ctx, remotePath, writer, newTransferOptions(opts))
+	return err
+}
+
+// DownloadFile downloads remote file to local path preserving permissions and modification time
+func (r *Runner) DownloadFile(ctx context.Context, remotePath, localPath string, opts ...TransferOption) error {
+	aTransfer, err := r.transfer(ctx)
+	if err != nil {
+		return err
+	}
+	return aTransfer.downloadFile(ctx, remotePath, localPath, newTransferOptions(opts))
+}
+
+// DownloadDir recursively downloads remote directory to local path
+func (r *Runner) DownloadDir(ctx context.Context, remoteDir, localDir string, opts ...TransferOption) error {
+	aTransfer, err := r.transfer(ctx)
+	if err != nil {
+		return err
+	}
+	return aTransfer.downloadDir(ctx, remoteDir, localDir, newTransferOptions(opts))
+}
+
+// transfer returns sftp transfer sharing runner ssh client
+func (r *Runner) transfer(ctx context.Context) (*transfer, error) {
+	if err := r.initIfNeeded(ctx); err != nil {
+		return nil, err
+	}
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.sftp != nil {
+		return r.sftp, nil
+	}
+	if r.client == nil {
+		return nil, fmt.Errorf("ssh client was not connected: %v", r.host)
+	}
+	client, err := sftp.NewClient(r.client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start sftp: %v, %w", r.host, err)
+	}
+	r.sftp = &transfer{client: client}
+	return r.sftp, nil
+}
+
+func (t *transfer) upload(ctx context.Context, reader io.Reader, size int64, remotePath string, options *transferOptions) (err error) {
+	mode := options.mode
+	if mode == 0 {
+		mode = 0644
+	}
+	writer, err := t.client.OpenFile(remotePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return fmt.Errorf("failed to create %v: %w", remotePath, err)
+	}
+	_, err = copyWithProgress(ctx, writer, reader, remotePath, size, options.progress)
+	if e := writer.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return err
+	}
+	if err = t.client.Chmod(remotePath, mode.Perm()); err != nil {
+		return err
+	}
+	if !options.modTime.IsZero() {
+		return t.client.Chtimes(remotePath, options.modTime, options.modTime)
+	}
+	return nil
+}
+
+func (t *transfer) uploadFile(ctx context.Context, localPath, remotePath string, options *transferOptions) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	fileOptions := *options
+	fileOptions.mode = info.Mode()
+	if fileOptions.modTime.IsZero() {
+		fileOptions.modTime = info.ModTime()
+	}
+	return t.upload(ctx, file, info.Size(), remotePath, &fileOptions)
+}
+
+func (t *transfer) uploadDir(ctx context.Context, localDir, remoteDir string, options *transferOptions) error {
+	var dirs []os.FileInfo
+	var dirPaths []string
+	err := filepath.Walk(localDir, func(localPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(localDir, localPath)
+		if err != nil {
+			return err
+		}
+		remotePath := path.Join(remoteDir, filepath.ToSlash(relative))
+		if info.IsDir() {
+			dirs = append(dirs, info)
+			dirPaths = append(dirPaths, remotePath)
+			return t.client.MkdirAll(remotePath)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return t.uploadFile(ctx, localPath, remotePath, options)
+	})
+	if err != nil {
+		return err
+	}
+	// apply directory attributes last, writing files would update their mtime
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err = t.client.Chmod(dirPaths[i], dirs[i].Mode().Perm()); err != nil {
+			return err
+		}
+		if err = t.client.Chtimes(dirPaths[i], dirs[i].ModTime(), dirs[i].ModTime()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *transfer) download(ctx context.Context, remotePath string, writer io.Writer, options *transferOptions) (os.FileInfo, error) {
+	reader, err := t.client.Open(remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %v: %w", remotePath, err)
+	}
+	defer reader.Close()
+	info, err := reader.Stat()
+	if err != nil {
+		return nil, err
+	}
+	_, err = copyWithProgress(ctx, writer, reader, remotePath, info.Size(), options.progress)
+	return info, err
+}
+
+func (t *transfer) downloadFile(ctx context.Context, remotePath, localPath string, options *transferOptions) error {
+	file, err := os.OpenFile(localPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := t.download(ctx, remotePath, file, options)
+	if e := file.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Chmod(localPath, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(localPath, info.ModTime(), info.ModTime())
+}
+
+func (t *transfer) downloadDir(ctx context.Context, remoteDir, localDir string, options *transferOptions) error {
+	walker := t.client.Walk(remoteDir)
+	var dirs []os.FileInfo
+	var dirPaths []string
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(remoteDir, walker.Path())
+		if err != nil {
+			return err
+		}
+		localPath := filepath.Join(localDir, relative)
+		info := walker.Stat()
+		if info.IsDir() {
+			if err = os.MkdirAll(localPath, 0755); err != nil {
+				return err
+			}
+			dirs = append(dirs, info)
+			dirPaths = append(dirPaths, localPath)
+			continue
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		if err = t.downloadFile(ctx, walker.Path(), localPath, options); err != nil {
+			return err
+		}
+	}
+	// apply directory attributes last, writing files would update their mtime
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirPaths[i], dirs[i].Mode().Perm()); err != nil {
+			return err
+		}
+		if err := os.Chtimes(dirPaths[i], dirs[i].ModTime(), dirs[i].ModTime()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *transfer) Close() error {
+	return t.client.Close()
+}
+
+func copyWithProgress(ctx context.Context, writer io.Writer, reader io.Reader, location string, total int64, progress Progress) (int64, error) {
+	buf := make([]byte, transferBufferSize)
+	var transferred int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return transferred, err
+		}
+		n, err := reader.Read(buf)
+		if n > 0 {
+			written, writeErr := writer.Write(buf[:n])
+			transferred += int64(written)
+			if writeErr != nil {
+				return transferred, writeErr
+			}
+			if written != n {
+				return transferred, io.ErrShortWrite
+			}
+			if progress != nil {
+				progress(location, transferred, total)
+			}
+		}
+		if err == io.EOF {
+			return transferred, nil
+		}
+		if err != nil {
+			return transferred, err
+		}
+	}
+}
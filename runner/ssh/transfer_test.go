@@ -0,0 +1,86 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTransfer(t *testing.T) *transfer {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+	server, err := sftp.NewServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{serverReader, serverWriter})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve()
+	client, err := sftp.NewClientPipe(clientReader, clientWriter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret := &transfer{client: client}
+	t.Cleanup(func() {
+		_ = server.Close()
+		_ = ret.Close()
+	})
+	return ret
+}
+
+func TestTransfer(t *testing.T) {
+	ctx := context.Background()
+	aTransfer := newTestTransfer(t)
+	baseDir := t.TempDir()
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	var progress []int64
+	remoteFile := filepath.Join(baseDir, "remote.txt")
+	options := newTransferOptions([]TransferOption{WithModTime(modTime), WithProgress(func(path string, transferred, total int64) {
+		progress = append(progress, transferred)
+	})})
+	options.mode = 0600
+	err := aTransfer.upload(ctx, strings.NewReader("hello"), -1, remoteFile, options)
+	if !assert.Nil(t, err) {
+		return
+	}
+	info, err := os.Stat(remoteFile)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	assert.True(t, modTime.Equal(info.ModTime()))
+	assert.Equal(t, []int64{5}, progress)
+
+	writer := new(bytes.Buffer)
+	_, err = aTransfer.download(ctx, remoteFile, writer, newTransferOptions(nil))
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", writer.String())
+
+	localDir := filepath.Join(baseDir, "local")
+	assert.Nil(t, os.MkdirAll(filepath.Join(localDir, "sub"), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(localDir, "sub", "run.sh"), []byte("echo"), 0750))
+	remoteDir := filepath.Join(baseDir, "remote")
+	assert.Nil(t, aTransfer.uploadDir(ctx, localDir, remoteDir, newTransferOptions(nil)))
+	downloadDir := filepath.Join(baseDir, "download")
+	assert.Nil(t, aTransfer.downloadDir(ctx, remoteDir, downloadDir, newTransferOptions(nil)))
+
+	expect, err := os.Stat(filepath.Join(localDir, "sub", "run.sh"))
+	assert.Nil(t, err)
+	actual, err := os.Stat(filepath.Join(downloadDir, "sub", "run.sh"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, expect.Mode().Perm(), actual.Mode().Perm())
+	assert.True(t, expect.ModTime().Truncate(time.Second).Equal(actual.ModTime().Truncate(time.Second)))
+	data, err := os.ReadFile(filepath.Join(downloadDir, "sub", "run.sh"))
+	assert.Nil(t, err)
+	assert.Equal(t, "echo", string(data))
+}
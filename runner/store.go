@@ -0,0 +1,120 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+type (
+	// HistoryStore represents a command history store
+	HistoryStore interface {
+		// Append saves a completed command
+		Append(command *Command) error
+		// Load returns saved commands in the order they were appended
+		Load() ([]*Command, error)
+	}
+
+	// MemoryStore represents an in-memory history store
+	MemoryStore struct {
+		mux      sync.Mutex
+		commands []*Command
+	}
+
+	// FileStore represents a history store writing each command as a JSON line, the file can be read after a crash
+	FileStore struct {
+		mux      sync.Mutex
+		location string
+	}
+)
+
+// Append saves a completed command
+func (s *MemoryStore) Append(command *Command) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.commands = append(s.commands, command)
+	return nil
+}
+
+// Load returns saved commands
+func (s *MemoryStore) Load() ([]*Command, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return append([]*Command{}, s.commands...), nil
+}
+
+// NewMemoryStore creates an in-memory history store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Append writes a completed command as a JSON line and syncs the file
+func (s *FileStore) Append(command *Command) error {
+	data, err := json.Marshal(command)
+	if err != nil {
+		return fmt.Errorf("failed to encode command %q: %w", command.Stdin, err)
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	file, err := os.OpenFile(s.location, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history %v: %w", s.location, err)
+	}
+	if _, err = file.Write(append(data, '\n')); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write history %v: %w", s.location, err)
+	}
+	return nil
+}
+
+// Load reads saved commands, a missing file has no commands; an incomplete last line, left by a crash while writing, is skipped
+func (s *FileStore) Load() ([]*Command, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	file, err := os.Open(s.location)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open history %v: %w", s.location, err)
+	}
+	defer file.Close()
+	var ret []*Command
+	reader := bufio.NewReader(file)
+	for lineNo := 1; ; lineNo++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to read history %v: %w", s.location, err)
+		}
+		complete := err == nil
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			command := &Command{}
+			if decodeErr := json.Unmarshal(line, command); decodeErr != nil {
+				if !complete {
+					break
+				}
+				return nil, fmt.Errorf("failed to decode history %v line %d: %w", s.location, lineNo, decodeErr)
+			}
+			ret = append(ret, command)
+		}
+		if !complete {
+			break
+		}
+	}
+	return ret, nil
+}
+
+// NewFileStore creates a JSONL file history store
+func NewFileStore(location string) *FileStore {
+	return &FileStore{location: location}
+}
@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// EventType represents stream event type
+type EventType string
+
+const (
+	// EventStdout represents stdout chunk
+	EventStdout EventType = "stdout"
+	// EventStderr represents stderr chunk
+	EventStderr EventType = "stderr"
+	// EventLine represents a complete stdout or stderr line
+	EventLine EventType = "line"
+	// EventExit represents command completion, it is the last event of a successful run
+	EventExit EventType = "exit"
+	// EventError represents run error, it is the last event of a failed run
+	EventError EventType = "error"
+)
+
+// Event represents command stream event
+type Event struct {
+	Type      EventType
+	CommandID string
+	Time      time.Time
+	Stream    Stream  // stream of chunk and line events
+	Data      string  // chunk or line without the line break
+	ExitCode  int     // exit event code
+	Result    *Result // exit event result
+	Err       error   // error event cause
+}
+
+// Events runs supplied command and returns a channel of its events, the channel is closed after the exit or error event.
+// Events are delivered as output is read, so the command is read no faster than the channel is consumed;
+// the channel has to be drained or ctx cancelled. Runners that do not report chunks as they read
+// (see WithChunkListener) have their output streamed after completion.
+func Events(ctx context.Context, runner Runner, command string, options ...Option) <-chan *Event {
+	events := make(chan *Event)
+	aStream := &stream{ctx: ctx, events: events, id: newCommandID(), lines: map[Stream]string{}}
+	go func() {
+		defer close(events)
+		options = append(options, WithChunkListener(aStream.chunk))
+		result, err := RunResult(ctx, runner, command, options...)
+		if !aStream.streamed && result != nil {
+			aStream.chunk(Stdout, result.Stdout)
+			aStream.chunk(Stderr, result.Stderr)
+		}
+		aStream.flushLines()
+		if err != nil {
+			aStream.emit(&Event{Type: EventError, Err: err})
+			return
+		}
+		aStream.emit(&Event{Type: EventExit, ExitCode: result.ExitCode, Result: result})
+	}()
+	return events
+}
+
+type stream struct {
+	ctx      context.Context
+	events   chan *Event
+	id       string
+	streamed bool
+	lines    map[Stream]string
+}
+
+func (s *stream) chunk(aStream Stream, data string) {
+	if data == "" {
+		return
+	}
+	s.streamed = true
+	eventType := EventStdout
+	if aStream == Stderr {
+		eventType = EventStderr
+	}
+	s.emit(&Event{Type: eventType, Stream: aStream, Data: data})
+	pending := s.lines[aStream] + data
+	for {
+		index := strings.IndexByte(pending, '\n')
+		if index == -1 {
+			break
+		}
+		s.emit(&Event{Type: EventLine, Stream: aStream, Data: strings.TrimSuffix(pending[:index], "\r")})
+		pending = pending[index+1:]
+	}
+	s.lines[aStream] = pending
+}
+
+func (s *stream) flushLines() {
+	for _, aStream := range []Stream{Stdout, Stderr} {
+		if pending := s.lines[aStream]; pending != "" {
+			s.emit(&Event{Type: EventLine, Stream: aStream, Data: strings.TrimSuffix(pending, "\r")})
+		}
+		delete(s.lines, aStream)
+	}
+}
+
+func (s *stream) emit(event *Event) {
+	event.CommandID = s.id
+	event.Time = time.Now()
+	select {
+	case s.events <- event:
+	case <-s.ctx.Done():
+	}
+}
+
+func newCommandID() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
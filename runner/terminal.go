@@ -0,0 +1,31 @@
+package runner
+
+import "errors"
+
+// ErrResizeNotSupported is returned when a runner has no terminal that can be resized
+var ErrResizeNotSupported = errors.New("resize not supported")
+
+// Resizer represents a runner running its shell on a terminal that can be resized
+type Resizer interface {
+	//Resize changes terminal size, the size also applies to terminals opened later, e.g. after reconnecting
+	Resize(cols, rows int) error
+	//Size returns terminal size
+	Size() (cols, rows int)
+}
+
+// Resize changes terminal size of a runner implementing Resizer, otherwise returns ErrResizeNotSupported
+func Resize(runner Runner, cols, rows int) error {
+	resizer, ok := runner.(Resizer)
+	if !ok {
+		return ErrResizeNotSupported
+	}
+	return resizer.Resize(cols, rows)
+}
+
+// Size returns terminal size of a runner implementing Resizer, zero otherwise
+func Size(runner Runner) (cols, rows int) {
+	if resizer, ok := runner.(Resizer); ok {
+		return resizer.Size()
+	}
+	return 0, 0
+}
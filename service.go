@@ -2,8 +2,10 @@ package gosh
 
 import (
 	"context"
+	"github.com/viant/gosh/facts"
 	"github.com/viant/gosh/runner"
 	"strings"
+	"sync"
 )
 
 // Service represents a shell service
@@ -12,6 +14,14 @@ type Service struct {
 	osInfo *OSInfo
 	hwInfo *HardwareInfo
 	user   string
+
+	facts     *facts.Registry
+	dialect   string
+	factsMux  sync.Mutex
+	hostFacts *facts.Facts
+
+	middlewares []runner.Middleware
+	sendHooks   []runner.SendHook
 }
 
 func (s *Service) User() string {
@@ -27,6 +37,42 @@ func (s *Service) Run(ctx context.Context, command string, options ...runner.Opt
 	return s.runner.Run(ctx, command, options...)
 }
 
+// RunResult runs supplied command and returns its stdout, stderr, exit code and timing
+func (s *Service) RunResult(ctx context.Context, command string, options ...runner.Option) (*runner.Result, error) {
+	return runner.RunResult(ctx, s.runner, command, options...)
+}
+
+// Stream runs supplied command and returns a channel of its stdout, stderr, line, exit and error events,
+// the channel is closed after the exit or error event and has to be drained or ctx cancelled
+func (s *Service) Stream(ctx context.Context, command string, options ...runner.Option) <-chan *runner.Event {
+	return runner.Events(ctx, s.runner, command, options...)
+}
+
+// Start starts supplied command in the background and returns its job, the session stays usable while the job runs
+func (s *Service) Start(ctx context.Context, command string, options ...runner.Option) (*runner.Job, error) {
+	return runner.Start(ctx, s.runner, command, options...)
+}
+
+// Send sends data to stdin
+func (s *Service) Send(ctx context.Context, data []byte) (int, error) {
+	return s.runner.Send(ctx, data)
+}
+
+// History returns command history of runners implementing runner.HistoryRunner, otherwise nil
+func (s *Service) History() *runner.History {
+	return runner.HistoryOf(s.runner)
+}
+
+// Resize changes the terminal size of runners implementing runner.Resizer, otherwise returns runner.ErrResizeNotSupported
+func (s *Service) Resize(cols, rows int) error {
+	return runner.Resize(s.runner, cols, rows)
+}
+
+// Size returns the terminal size, zero when the runner has no terminal
+func (s *Service) Size() (cols, rows int) {
+	return runner.Size(s.runner)
+}
+
 // PID returns process id
 func (s *Service) PID() int {
 	return s.runner.PID()
@@ -42,6 +88,33 @@ func (s *Service) HardwareInfo() *HardwareInfo {
 	return s.hwInfo
 }
 
+// Facts returns host facts, facts are collected on the first call and cached afterwards
+func (s *Service) Facts(ctx context.Context) (*facts.Facts, error) {
+	s.factsMux.Lock()
+	defer s.factsMux.Unlock()
+	if s.hostFacts != nil {
+		return s.hostFacts, nil
+	}
+	return s.collectFacts(ctx)
+}
+
+// RefreshFacts recollects and caches host facts
+func (s *Service) RefreshFacts(ctx context.Context) (*facts.Facts, error) {
+	s.factsMux.Lock()
+	defer s.factsMux.Unlock()
+	return s.collectFacts(ctx)
+}
+
+func (s *Service) collectFacts(ctx context.Context) (*facts.Facts, error) {
+	target := &facts.Target{System: s.osInfo.System, Dialect: s.dialect}
+	hostFacts, err := s.facts.Collect(ctx, s.runner, target)
+	if err != nil {
+		return nil, err
+	}
+	s.hostFacts = hostFacts
+	return hostFacts, nil
+}
+
 func (s *Service) init(ctx context.Context) error {
 	return s.detectSystem(ctx)
 }
@@ -49,19 +122,8 @@ func (s *Service) init(ctx context.Context) error {
 func (s *Service) detectSystem(ctx context.Context) (err error) {
 	s.osInfo = &OSInfo{}
 	s.hwInfo = &HardwareInfo{Architecture: "unknown"}
-	var e error
-	if s.osInfo.System, _, e = s.runner.Run(ctx, "uname -s"); err != nil {
-		err = e
-	}
-	s.osInfo.System = strings.TrimSpace(strings.TrimSpace(strings.ToLower(s.osInfo.System)))
-	if s.hwInfo.Hardware, _, e = s.runner.Run(ctx, "uname -m"); err != nil {
-		err = e
-	}
-	s.hwInfo.Hardware = strings.TrimSpace(strings.ToLower(s.hwInfo.Hardware))
-	checkCmd := "lsb_release -a"
-	if s.osInfo.System == "darwin" {
-		checkCmd = "sw_vers"
-	}
+	aDetector := &detector{runner: s.runner}
+	aDetector.detect(ctx, s.osInfo, s.hwInfo)
 	if isAmd64Architecture(s.hwInfo.Hardware) {
 		s.hwInfo.Architecture = "amd64"
 		s.hwInfo.Arch = "x64"
@@ -74,46 +136,8 @@ func (s *Service) detectSystem(ctx context.Context) (err error) {
 		s.hwInfo.Architecture = "arm64"
 		s.hwInfo.Arch = "x64"
 	}
-	output, _, e := s.runner.Run(ctx, checkCmd)
-	if e != nil {
-		err = e
-	}
-	lines := strings.Split(output, "\n")
-	for i := 0; i < len(lines); i++ {
-		line := lines[i]
-		pair := strings.Split(line, ":")
-		if len(pair) != 2 {
-			continue
-		}
-		var key = strings.Replace(strings.ToLower(pair[0]), " ", "", len(pair[0]))
-		var val = strings.Replace(strings.Trim(pair[1], " \t\r"), " ", "", len(line))
-		switch key {
-		case "distributorid":
-			s.osInfo.DistributorID = strings.ToLower(val)
-		case "productname":
-			s.osInfo.Name = strings.ToLower(val)
-		case "buildversion":
-			s.osInfo.Codename = strings.ToLower(val)
-		case "productversion", "release":
-			s.osInfo.Release = strings.ToLower(val)
-		}
-
-	}
-	if s.user, _, e = s.runner.Run(ctx, "echo $USER"); err != nil {
-		err = e
-	}
-	s.user = strings.TrimSpace(s.user)
-	if isNotFound(err) {
-		return nil
-	}
-	return err
-}
-
-func isNotFound(err error) bool {
-	if err == nil {
-		return false
-	}
-	return strings.Contains(strings.ToLower(err.Error()), "not found")
+	s.user, _ = aDetector.output(ctx, "echo $USER")
+	return aDetector.err
 }
 
 func isAmd64Architecture(candidate string) bool {
@@ -128,7 +152,15 @@ func isAppleArm64Architecture(hardware string) bool {
 }
 
 // New creates a new shell service
-func New(ctx context.Context, runner runner.Runner) (*Service, error) {
-	ret := &Service{runner: runner}
-	return ret, ret.init(ctx)
+func New(ctx context.Context, aRunner runner.Runner, options ...Option) (*Service, error) {
+	ret := &Service{runner: aRunner, dialect: facts.DialectPosix}
+	for _, option := range options {
+		option(ret)
+	}
+	if ret.facts == nil {
+		ret.facts = facts.New()
+	}
+	err := ret.init(ctx) // system detection runs on the runner, internal commands are not passed to the middleware
+	ret.runner = runner.WrapSend(runner.Wrap(ret.runner, ret.middlewares...), ret.sendHooks...)
+	return ret, err
 }
@@ -6,12 +6,15 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/viant/afs"
 	"github.com/viant/gosh"
+	"github.com/viant/gosh/runner"
 	"github.com/viant/gosh/runner/local"
 	"github.com/viant/gosh/runner/ssh"
 	"github.com/viant/scy/cred"
 	"os"
 	"path"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestService_Run(t *testing.T) {
@@ -31,7 +34,25 @@ func TestService_Run(t *testing.T) {
 	assert.True(t, len(output) > 0)
 }
 
-func ExampleLocalRun() {
+func TestService_Middleware(t *testing.T) {
+	var commands []string
+	observe := runner.Observe(func(command string, elapsed time.Duration, result *runner.Result, err error) {
+		commands = append(commands, command)
+	})
+	srv, err := gosh.New(context.Background(), local.New(), gosh.WithMiddleware(observe))
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer srv.Close()
+	assert.Empty(t, commands) // system detection does not run through the middleware
+	assert.NotEmpty(t, srv.OsInfo().System)
+	output, _, err := srv.Run(context.Background(), "echo middleware")
+	assert.Nil(t, err)
+	assert.Equal(t, "middleware", strings.TrimSpace(output))
+	assert.Equal(t, []string{"echo middleware"}, commands)
+}
+
+func Example_localRun() {
 	srv, err := gosh.New(context.Background(), local.New())
 	if err != nil {
 		return
@@ -41,14 +62,14 @@ func ExampleLocalRun() {
 	println(output)
 }
 
-func ExampleRemoveRun() {
+func Example_removeRun() {
 	host := "localhost"
 	privateKeyBytes := getKeyLocation(host)
 	if privateKeyBytes == nil {
 		return
 	}
 	sshCred := cred.SSH{
-		PrivateKey: privateKeyBytes,
+		PrivateKeyPayload: privateKeyBytes,
 		Basic: cred.Basic{
 			Username: os.Getenv("USER"),
 		},
@@ -0,0 +1,152 @@
+package term
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxColumn limits cursor movement, a terminal would stop at its last column
+const maxColumn = 4096
+
+type (
+	// normalizer applies carriage returns, backspaces and line erasing to the current line and drops other control functions;
+	// when styled, SGR and OSC 8 hyperlinks set the style of printed characters and lines are collected as spans
+	normalizer struct {
+		output strings.Builder
+		line   []cell
+		col    int
+		styled bool
+		style  *Style
+		spans  Spans
+	}
+
+	cell struct {
+		r     rune
+		style *Style
+	}
+)
+
+func (n *normalizer) print(r rune) {
+	for n.col > len(n.line) {
+		n.line = append(n.line, cell{r: ' '})
+	}
+	if n.col == len(n.line) {
+		n.line = append(n.line, cell{r: r, style: n.style})
+	} else {
+		n.line[n.col] = cell{r: r, style: n.style}
+	}
+	n.col++
+}
+
+func (n *normalizer) execute(r rune) {
+	switch r {
+	case '\n', '\v', '\f':
+		n.flush()
+		if n.styled {
+			n.spans = n.spans.append("\n", nil)
+		} else {
+			n.output.WriteByte('\n')
+		}
+	case '\r':
+		n.col = 0
+	case '\b':
+		n.col = max(n.col-1, 0)
+	case '\t':
+		n.print('\t')
+	}
+}
+
+func (n *normalizer) escape(intermediate string, final rune) {
+	if intermediate == "" && final == 'E' { // NEL
+		n.execute('\n')
+	}
+}
+
+func (n *normalizer) control(sequence *controlSequence) {
+	if sequence.private != 0 || sequence.intermediate != "" {
+		return
+	}
+	switch sequence.final {
+	case 'm': // SGR
+		if n.styled {
+			n.style = n.style.apply(sequence.raw)
+		}
+	case 'C': // CUF
+		n.col = min(n.col+sequence.param(0, 1), maxColumn)
+	case 'D': // CUB
+		n.col = max(n.col-sequence.param(0, 1), 0)
+	case 'G': // CHA
+		n.col = min(sequence.param(0, 1), maxColumn) - 1
+	case 'K': // EL
+		switch sequence.param(0, 0) {
+		case 0:
+			n.line = n.line[:min(n.col, len(n.line))]
+		case 1:
+			for i := 0; i < min(n.col+1, len(n.line)); i++ {
+				n.line[i] = cell{r: ' '}
+			}
+		case 2:
+			n.line = n.line[:0]
+		}
+	}
+}
+
+func (n *normalizer) command(kind rune, data string) {
+	if n.styled && kind == ']' && strings.HasPrefix(data, "8;") { // OSC 8 ; params ; URI
+		if index := strings.IndexByte(data[2:], ';'); index != -1 {
+			n.style = n.style.withLink(data[2+index+1:])
+		}
+	}
+}
+
+func (n *normalizer) flush() {
+	if n.styled {
+		for start, end := 0, 0; start < len(n.line); start = end {
+			run := []rune{}
+			for end = start; end < len(n.line) && n.line[end].style.equal(n.line[start].style); end++ {
+				run = append(run, n.line[end].r)
+			}
+			n.spans = n.spans.append(string(run), n.line[start].style)
+		}
+		n.line = n.line[:0]
+		n.col = 0
+		return
+	}
+	for _, c := range n.line {
+		n.output.WriteRune(c.r)
+	}
+	n.line = n.line[:0]
+	n.col = 0
+}
+
+// Normalize returns text a terminal would show on each line: escape, control (CSI) and string (OSC, DCS, APC, PM, SOS)
+// sequences of ECMA-48 in 7-bit and 8-bit (C1) form are removed, carriage returns, backspaces and line erasing rewrite
+// the current line, other C0 controls but line feed and tab are dropped. Each call is independent, a sequence split
+// across calls is not recognised
+func Normalize(text string) string {
+	if isNormalized(text) {
+		return text
+	}
+	aNormalizer := &normalizer{}
+	aNormalizer.output.Grow(len(text))
+	aParser := newParser(aNormalizer)
+	aParser.write([]byte(text))
+	if len(aParser.pending) > 0 {
+		aNormalizer.print(utf8.RuneError)
+	}
+	aNormalizer.flush()
+	return aNormalizer.output.String()
+}
+
+// isNormalized returns true if text has no control characters but line feed and tab
+func isNormalized(text string) bool {
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c < 0x20 && c != '\n' && c != '\t', c == 0x7f:
+			return false
+		case c == 0xc2 && i+1 < len(text) && text[i+1] >= 0x80 && text[i+1] <= 0x9f: // C1 control encoded in UTF-8
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package term
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	var testCases = []struct {
+		description string
+		input       string
+		expect      string
+	}{
+		{description: "plain text", input: "line1\n\tline2\n", expect: "line1\n\tline2\n"},
+		{description: "sgr", input: "\x1b[1;31mred\x1b[0m and \x1b[38;2;255;0;0mtrue\x1b[m", expect: "red and true"},
+		{description: "osc title with bel", input: "\x1b]0;user@host: ~\x07$ ls", expect: "$ ls"},
+		{description: "osc hyperlink with st", input: "\x1b]8;;https://viant.io\x1b\\link\x1b]8;;\x1b\\", expect: "link"},
+		{description: "bracketed paste", input: "\x1b[?2004hprompt$ \x1b[?2004l\r\n", expect: "prompt$ \n"},
+		{description: "carriage return rewrite", input: "10%\r50%\r100%\n", expect: "100%\n"},
+		{description: "shorter rewrite keeps the rest", input: "100%\r50", expect: "500%"},
+		{description: "crlf", input: "a\r\nb\r\n", expect: "a\nb\n"},
+		{description: "erase line", input: "downloading...\r\x1b[Kdone", expect: "done"},
+		{description: "erase whole line", input: "abc\x1b[2Kx", expect: "   x"},
+		{description: "backspace", input: "abc\b\bX", expect: "aXc"},
+		{description: "backspace at line start", input: "\b\bab", expect: "ab"},
+		{description: "cursor movement", input: "abc\x1b[2DX\x1b[3CY\x1b[1GZ", expect: "ZXc  Y"},
+		{description: "c1 csi and osc", input: "a\u009b31mb\u009d0;title\u009cc", expect: "abc"},
+		{description: "c1 next line", input: "a\u0085b", expect: "a\nb"},
+		{description: "dcs and apc", input: "a\x1bP1$r0m\x1b\\b\x1b_data\x1b\\c", expect: "abc"},
+		{description: "charset designation and keypad", input: "\x1b(Ba\x1b=b\x1b>", expect: "ab"},
+		{description: "other c0 dropped", input: "bell\x07\x00\x0e!", expect: "bell!"},
+		{description: "cancelled sequence", input: "a\x1b[12\x18b", expect: "ab"},
+		{description: "incomplete sequence", input: "a\x1b[31", expect: "a"},
+		{description: "huge column", input: "a\x1b[999999999Cb", expect: "a" + strings.Repeat(" ", maxColumn-1) + "b"},
+		{description: "unicode", input: "żółw\r\x1b[Cé", expect: "żéłw"},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expect, Normalize(testCase.input), testCase.description)
+	}
+}
+
+func FuzzNormalize(f *testing.F) {
+	for _, seed := range []string{
+		"plain\ttext\n",
+		"\x1b[1;31mred\x1b[0m",
+		"\x1b]0;title\x07\x1b]8;;https://viant.io\x1b\\link\x1b]8;;\x1b\\",
+		"\x1b[?2004h$ \x1b[?2004l\r\n",
+		"10%\r50%\r\x1b[K100%\n",
+		"abc\b\bX\x1b[2D\x1b[3C\x1b[1G",
+		"\u009b31m\u009d0;t\u009c\u0085\u0090q\u009c",
+		"\x1bP1$r0m\x1b\\\x1b_apc\x1b\\\x1b^pm\x1b\\\x1bXsos\x1b\\",
+		"\x1b(B\x1b)0\x1b#8\x1b7\x1b8\x1bc",
+		"a\x1b[12\x18b\x1b]2;x\x1ab",
+		"\xff\xfe\xc2\x9b\xc2",
+		"__GOSH_0011223344556677__:0:/tmp\r\n\x1b]0;host\x07$ ",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		output := Normalize(input)
+		for _, r := range output {
+			if r == '\n' || r == '\t' {
+				continue
+			}
+			if r < 0x20 || r == 0x7f || (r >= 0x80 && r <= 0x9f) {
+				t.Fatalf("control %U left in %q for %q", r, output, input)
+			}
+		}
+		if isNormalized(input) && output != input {
+			t.Fatalf("text without controls changed: %q -> %q", input, output)
+		}
+		if !utf8.ValidString(input) {
+			return
+		}
+		if again := Normalize(output); again != output {
+			t.Fatalf("not idempotent: %q -> %q -> %q", input, output, again)
+		}
+		if strings.Count(output, "\n") > strings.Count(input, "\n")+strings.Count(input, "\v")+strings.Count(input, "\f")+strings.Count(input, "\u0085")+strings.Count(input, "\x1bE") {
+			t.Fatalf("line feeds added: %q -> %q", input, output)
+		}
+	})
+}
+
+func BenchmarkNormalize(b *testing.B) {
+	chunk := strings.Repeat("\x1b[32mINFO\x1b[0m building target\r\x1b[K[=====>    ] 50%\r\n", 64)
+	b.SetBytes(int64(len(chunk)))
+	for i := 0; i < b.N; i++ {
+		Normalize(chunk)
+	}
+}
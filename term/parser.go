@@ -0,0 +1,243 @@
+package term
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxStringLength limits collected OSC/DCS data, the rest is discarded
+	maxStringLength = 4096
+	esc             = 0x1b
+)
+
+// parser states
+const (
+	stateGround = iota
+	stateEscape
+	stateControl
+	stateString
+)
+
+type (
+	// handler receives text and control functions recognised by parser
+	handler interface {
+		// print handles a printable character
+		print(r rune)
+		// execute handles a C0 control character
+		execute(r rune)
+		// escape handles an escape sequence, C1 controls are reported as their ESC Fe equivalent
+		escape(intermediate string, final rune)
+		// control handles a control sequence (CSI)
+		control(sequence *controlSequence)
+		// command handles a control string, kind is ']' for OSC, 'P' for DCS, 'X' for SOS, '^' for PM and '_' for APC
+		command(kind rune, data string)
+	}
+
+	// controlSequence represents a CSI control sequence
+	controlSequence struct {
+		private      rune // parameter prefix, one of '<', '=', '>', '?', or zero
+		raw          string
+		intermediate string
+		final        rune
+	}
+
+	// parser splits terminal output into text and ECMA-48 control functions, it keeps state across writes
+	parser struct {
+		handler      handler
+		state        int
+		pending      []byte // incomplete UTF-8 sequence
+		intermediate strings.Builder
+		params       []byte
+		private      rune
+		sequence     controlSequence
+		kind         rune // control string kind
+		data         strings.Builder
+		stringEscape bool // ESC seen inside a control string
+	}
+)
+
+// param returns index-th parameter, defaultValue when it is missing, empty or zero;
+// sub parameters separated with ':' are ignored
+func (c *controlSequence) param(index, defaultValue int) int {
+	value := c.raw
+	for ; index > 0; index-- {
+		i := strings.IndexByte(value, ';')
+		if i == -1 {
+			return defaultValue
+		}
+		value = value[i+1:]
+	}
+	if i := strings.IndexAny(value, ";:"); i != -1 {
+		value = value[:i]
+	}
+	ret, err := strconv.Atoi(value)
+	if err != nil || ret <= 0 {
+		return defaultValue
+	}
+	return ret
+}
+
+// params returns all parameters, missing ones as zero
+func (c *controlSequence) params() []int {
+	if c.raw == "" {
+		return nil
+	}
+	fields := strings.Split(c.raw, ";")
+	ret := make([]int, len(fields))
+	for i, field := range fields {
+		if j := strings.IndexByte(field, ':'); j != -1 {
+			field = field[:j]
+		}
+		ret[i], _ = strconv.Atoi(field)
+	}
+	return ret
+}
+
+// write parses data, an incomplete UTF-8 sequence or control function is completed by the next write
+func (p *parser) write(data []byte) {
+	if len(p.pending) > 0 {
+		data = append(p.pending, data...)
+		p.pending = nil
+	}
+	for len(data) > 0 {
+		if c := data[0]; p.state == stateGround && c >= 0x20 && c < 0x7f {
+			p.handler.print(rune(c))
+			data = data[1:]
+			continue
+		}
+		r, size := utf8.DecodeRune(data)
+		if r == utf8.RuneError && size == 1 && !utf8.FullRune(data) {
+			p.pending = append([]byte{}, data...)
+			return
+		}
+		data = data[size:]
+		p.next(r)
+	}
+}
+
+func (p *parser) next(r rune) {
+	switch p.state {
+	case stateGround:
+		p.ground(r)
+	case stateEscape:
+		p.escape(r)
+	case stateControl:
+		p.control(r)
+	case stateString:
+		p.string(r)
+	}
+}
+
+func (p *parser) ground(r rune) {
+	switch {
+	case r == esc:
+		p.startEscape()
+	case r < 0x20:
+		p.handler.execute(r)
+	case r == 0x7f:
+	case r >= 0x80 && r <= 0x9f:
+		p.c1(r)
+	default:
+		p.handler.print(r)
+	}
+}
+
+// c1 handles 8-bit control as its 7-bit ESC Fe equivalent
+func (p *parser) c1(r rune) {
+	p.intermediate.Reset()
+	p.escape(r - 0x40)
+}
+
+func (p *parser) startEscape() {
+	p.state = stateEscape
+	p.intermediate.Reset()
+}
+
+func (p *parser) escape(r rune) {
+	switch {
+	case r == 0x18 || r == 0x1a: // CAN and SUB cancel the sequence
+		p.state = stateGround
+	case r == esc:
+		p.startEscape()
+	case r < 0x20:
+		p.handler.execute(r)
+	case r >= 0x20 && r <= 0x2f:
+		p.state = stateEscape
+		p.intermediate.WriteRune(r)
+	case p.intermediate.Len() == 0 && r == '[':
+		p.state = stateControl
+		p.params = p.params[:0]
+		p.private = 0
+	case p.intermediate.Len() == 0 && (r == ']' || r == 'P' || r == 'X' || r == '^' || r == '_'):
+		p.state = stateString
+		p.kind = r
+		p.data.Reset()
+		p.stringEscape = false
+	case p.intermediate.Len() == 0 && r == '\\': // ST without a control string
+		p.state = stateGround
+	case r >= 0x30 && r <= 0x7e:
+		p.state = stateGround
+		p.handler.escape(p.intermediate.String(), r)
+	default:
+		p.state = stateGround
+	}
+}
+
+func (p *parser) control(r rune) {
+	switch {
+	case r == 0x18 || r == 0x1a:
+		p.state = stateGround
+	case r == esc:
+		p.startEscape()
+	case r < 0x20:
+		p.handler.execute(r)
+	case r >= '<' && r <= '?' && len(p.params) == 0 && p.private == 0 && p.intermediate.Len() == 0:
+		p.private = r
+	case r >= 0x30 && r <= 0x3f:
+		p.params = append(p.params, byte(r))
+	case r >= 0x20 && r <= 0x2f:
+		p.intermediate.WriteRune(r)
+	case r >= 0x40 && r <= 0x7e:
+		p.state = stateGround
+		p.sequence = controlSequence{private: p.private, raw: string(p.params), intermediate: p.intermediate.String(), final: r}
+		p.handler.control(&p.sequence)
+	default:
+		p.state = stateGround
+	}
+}
+
+func (p *parser) string(r rune) {
+	if p.stringEscape {
+		p.stringEscape = false
+		p.endString()
+		if r == '\\' {
+			return
+		}
+		p.startEscape()
+		p.escape(r)
+		return
+	}
+	switch {
+	case r == esc:
+		p.stringEscape = true
+	case r == 0x9c, r == 0x07 && p.kind == ']':
+		p.endString()
+	case r == 0x18 || r == 0x1a:
+		p.state = stateGround
+	default:
+		if p.data.Len() < maxStringLength {
+			p.data.WriteRune(r)
+		}
+	}
+}
+
+func (p *parser) endString() {
+	p.state = stateGround
+	p.handler.command(p.kind, p.data.String())
+}
+
+func newParser(handler handler) *parser {
+	return &parser{handler: handler}
+}
@@ -0,0 +1,488 @@
+package term
+
+import (
+	"strings"
+)
+
+const (
+	defaultScrollback = 1000
+	tabWidth          = 8
+)
+
+type (
+	// Screen represents a VT100/xterm screen model, terminal output written to it is applied to a grid of
+	// cols x rows cells with scrollback, alternate screen and cursor tracking
+	Screen struct {
+		cols, rows    int
+		main          [][]rune
+		alternate     [][]rune
+		alternateMode bool
+		scrollback    [][]rune
+		maxScrollback int
+		row, col      int
+		wrapPending   bool
+		autoWrap      bool
+		top, bottom   int // scroll region, inclusive
+		saved         cursor
+		savedMain     cursor // cursor saved when switching to the alternate screen
+		parser        *parser
+	}
+
+	cursor struct {
+		row, col int
+	}
+
+	// ScreenOption represents screen option
+	ScreenOption func(s *Screen)
+)
+
+// WithScrollback sets the number of lines scrolled off the main screen that are kept, 1000 by default
+func WithScrollback(lines int) ScreenOption {
+	return func(s *Screen) {
+		s.maxScrollback = lines
+	}
+}
+
+// Write applies terminal output to the screen, it implements io.Writer
+func (s *Screen) Write(data []byte) (int, error) {
+	s.parser.write(data)
+	return len(data), nil
+}
+
+// WriteString applies terminal output to the screen
+func (s *Screen) WriteString(text string) (int, error) {
+	return s.Write([]byte(text))
+}
+
+// Size returns screen size
+func (s *Screen) Size() (cols, rows int) {
+	return s.cols, s.rows
+}
+
+// Cursor returns zero based cursor position
+func (s *Screen) Cursor() (row, col int) {
+	return s.row, s.col
+}
+
+// AlternateScreen returns true if the alternate screen used by full screen programs is shown
+func (s *Screen) AlternateScreen() bool {
+	return s.alternateMode
+}
+
+// Lines returns visible lines without trailing spaces
+func (s *Screen) Lines() []string {
+	return renderLines(s.grid())
+}
+
+// Scrollback returns lines scrolled off the main screen, the oldest first
+func (s *Screen) Scrollback() []string {
+	return renderLines(s.scrollback)
+}
+
+// String returns visible text, trailing empty lines are removed
+func (s *Screen) String() string {
+	return joinLines(s.Lines())
+}
+
+// Text returns scrollback followed by visible text, trailing empty lines are removed
+func (s *Screen) Text() string {
+	return joinLines(append(s.Scrollback(), s.Lines()...))
+}
+
+// Resize changes screen size, lines are truncated or padded and top lines are scrolled off to keep the cursor visible
+func (s *Screen) Resize(cols, rows int) {
+	if cols <= 0 || rows <= 0 {
+		return
+	}
+	if shift := s.row - rows + 1; shift > 0 {
+		if s.alternateMode {
+			s.alternate = s.alternate[shift:]
+		} else {
+			s.pushScrollback(s.main[:shift]...)
+			s.main = s.main[shift:]
+		}
+		s.row -= shift
+	}
+	s.cols, s.rows = cols, rows
+	s.main = resizeGrid(s.main, cols, rows)
+	if s.alternate != nil {
+		s.alternate = resizeGrid(s.alternate, cols, rows)
+	}
+	s.top, s.bottom = 0, rows-1
+	s.col = min(s.col, cols-1)
+	s.wrapPending = false
+}
+
+func (s *Screen) grid() [][]rune {
+	if s.alternateMode {
+		return s.alternate
+	}
+	return s.main
+}
+
+func (s *Screen) print(r rune) {
+	if s.wrapPending {
+		s.wrapPending = false
+		s.col = 0
+		s.lineFeed()
+	}
+	s.grid()[s.row][s.col] = r
+	if s.col == s.cols-1 {
+		s.wrapPending = s.autoWrap
+		return
+	}
+	s.col++
+}
+
+func (s *Screen) execute(r rune) {
+	switch r {
+	case '\b':
+		s.moveTo(s.row, s.col-1)
+	case '\t':
+		s.moveTo(s.row, (s.col/tabWidth+1)*tabWidth)
+	case '\n', '\v', '\f':
+		// output captured without a terminal has no carriage returns, a line feed starts a new line
+		s.col = 0
+		s.wrapPending = false
+		s.lineFeed()
+	case '\r':
+		s.col = 0
+		s.wrapPending = false
+	}
+}
+
+func (s *Screen) escape(intermediate string, final rune) {
+	if intermediate != "" {
+		return // character set designation and other sequences do not change the grid
+	}
+	switch final {
+	case '7':
+		s.saved = cursor{row: s.row, col: s.col}
+	case '8':
+		s.moveTo(s.saved.row, s.saved.col)
+	case 'D': // IND
+		s.lineFeed()
+	case 'E': // NEL
+		s.col = 0
+		s.lineFeed()
+	case 'M': // RI
+		s.reverseLineFeed()
+	case 'c': // RIS
+		s.reset()
+	}
+}
+
+func (s *Screen) control(sequence *controlSequence) {
+	if sequence.intermediate != "" {
+		return
+	}
+	if sequence.private == '?' {
+		s.mode(sequence)
+		return
+	}
+	if sequence.private != 0 {
+		return
+	}
+	n := sequence.param(0, 1)
+	switch sequence.final {
+	case 'A': // CUU
+		s.moveTo(s.row-n, s.col)
+	case 'B', 'e': // CUD, VPR
+		s.moveTo(s.row+n, s.col)
+	case 'C', 'a': // CUF, HPR
+		s.moveTo(s.row, s.col+n)
+	case 'D': // CUB
+		s.moveTo(s.row, s.col-n)
+	case 'E': // CNL
+		s.moveTo(s.row+n, 0)
+	case 'F': // CPL
+		s.moveTo(s.row-n, 0)
+	case 'G', '`': // CHA, HPA
+		s.moveTo(s.row, n-1)
+	case 'H', 'f': // CUP, HVP
+		s.moveTo(n-1, sequence.param(1, 1)-1)
+	case 'd': // VPA
+		s.moveTo(n-1, s.col)
+	case 'I': // CHT
+		s.moveTo(s.row, (s.col/tabWidth+n)*tabWidth)
+	case 'Z': // CBT
+		s.moveTo(s.row, ((s.col+tabWidth-1)/tabWidth-n)*tabWidth)
+	case 'J': // ED
+		s.eraseDisplay(sequence.param(0, 0))
+	case 'K': // EL
+		s.eraseLine(sequence.param(0, 0))
+	case '@': // ICH
+		s.insertChars(n)
+	case 'P': // DCH
+		s.deleteChars(n)
+	case 'X': // ECH
+		line := s.grid()[s.row]
+		clearCells(line[s.col:min(s.col+n, s.cols)])
+	case 'L': // IL
+		s.insertLines(n)
+	case 'M': // DL
+		s.deleteLines(n)
+	case 'S': // SU
+		s.scrollUp(s.top, s.bottom, n)
+	case 'T': // SD
+		s.scrollDown(s.top, s.bottom, n)
+	case 'r': // DECSTBM
+		top, bottom := sequence.param(0, 1)-1, sequence.param(1, s.rows)-1
+		if top < bottom && bottom < s.rows {
+			s.top, s.bottom = top, bottom
+			s.moveTo(0, 0)
+		}
+	case 's':
+		s.saved = cursor{row: s.row, col: s.col}
+	case 'u':
+		s.moveTo(s.saved.row, s.saved.col)
+	}
+}
+
+// mode handles DEC private modes that change the grid
+func (s *Screen) mode(sequence *controlSequence) {
+	set := sequence.final == 'h'
+	if !set && sequence.final != 'l' {
+		return
+	}
+	for _, mode := range sequence.params() {
+		switch mode {
+		case 7:
+			s.autoWrap = set
+		case 47, 1047, 1049:
+			s.switchScreen(set, mode == 1049)
+		}
+	}
+}
+
+func (s *Screen) switchScreen(alternate, saveCursor bool) {
+	if alternate == s.alternateMode {
+		return
+	}
+	if alternate {
+		if saveCursor {
+			s.savedMain = cursor{row: s.row, col: s.col}
+		}
+		s.alternate = newGrid(s.cols, s.rows)
+		s.alternateMode = true
+		return
+	}
+	s.alternateMode = false
+	s.alternate = nil
+	if saveCursor {
+		s.moveTo(s.savedMain.row, s.savedMain.col)
+	}
+}
+
+func (s *Screen) command(kind rune, data string) {}
+
+func (s *Screen) moveTo(row, col int) {
+	s.row = clamp(row, 0, s.rows-1)
+	s.col = clamp(col, 0, s.cols-1)
+	s.wrapPending = false
+}
+
+func (s *Screen) lineFeed() {
+	if s.row == s.bottom {
+		s.scrollUp(s.top, s.bottom, 1)
+		return
+	}
+	if s.row < s.rows-1 {
+		s.row++
+	}
+}
+
+func (s *Screen) reverseLineFeed() {
+	if s.row == s.top {
+		s.scrollDown(s.top, s.bottom, 1)
+		return
+	}
+	if s.row > 0 {
+		s.row--
+	}
+}
+
+// scrollUp scrolls lines of the region up, lines leaving the top of the main screen are kept in scrollback
+func (s *Screen) scrollUp(top, bottom, n int) {
+	grid := s.grid()
+	n = min(n, bottom-top+1)
+	if top == 0 && !s.alternateMode {
+		s.pushScrollback(grid[:n]...)
+	}
+	copy(grid[top:], grid[top+n:bottom+1])
+	for i := bottom - n + 1; i <= bottom; i++ {
+		grid[i] = newLine(s.cols)
+	}
+}
+
+func (s *Screen) scrollDown(top, bottom, n int) {
+	grid := s.grid()
+	n = min(n, bottom-top+1)
+	copy(grid[top+n:bottom+1], grid[top:bottom+1-n])
+	for i := top; i < top+n; i++ {
+		grid[i] = newLine(s.cols)
+	}
+}
+
+func (s *Screen) insertLines(n int) {
+	if s.row < s.top || s.row > s.bottom {
+		return
+	}
+	s.scrollDown(s.row, s.bottom, n)
+	s.col = 0
+}
+
+func (s *Screen) deleteLines(n int) {
+	if s.row < s.top || s.row > s.bottom {
+		return
+	}
+	grid := s.grid()
+	n = min(n, s.bottom-s.row+1)
+	copy(grid[s.row:], grid[s.row+n:s.bottom+1])
+	for i := s.bottom - n + 1; i <= s.bottom; i++ {
+		grid[i] = newLine(s.cols)
+	}
+	s.col = 0
+}
+
+func (s *Screen) insertChars(n int) {
+	line := s.grid()[s.row]
+	n = min(n, s.cols-s.col)
+	copy(line[s.col+n:], line[s.col:])
+	clearCells(line[s.col : s.col+n])
+	s.wrapPending = false
+}
+
+func (s *Screen) deleteChars(n int) {
+	line := s.grid()[s.row]
+	n = min(n, s.cols-s.col)
+	copy(line[s.col:], line[s.col+n:])
+	clearCells(line[s.cols-n:])
+	s.wrapPending = false
+}
+
+func (s *Screen) eraseDisplay(mode int) {
+	grid := s.grid()
+	switch mode {
+	case 0:
+		clearCells(grid[s.row][s.col:])
+		for _, line := range grid[s.row+1:] {
+			clearCells(line)
+		}
+	case 1:
+		clearCells(grid[s.row][:s.col+1])
+		for _, line := range grid[:s.row] {
+			clearCells(line)
+		}
+	case 2:
+		for _, line := range grid {
+			clearCells(line)
+		}
+	case 3:
+		s.scrollback = nil
+	}
+}
+
+func (s *Screen) eraseLine(mode int) {
+	line := s.grid()[s.row]
+	switch mode {
+	case 0:
+		clearCells(line[s.col:])
+	case 1:
+		clearCells(line[:s.col+1])
+	case 2:
+		clearCells(line)
+	}
+}
+
+func (s *Screen) pushScrollback(lines ...[]rune) {
+	if s.maxScrollback <= 0 {
+		return
+	}
+	s.scrollback = append(s.scrollback, lines...)
+	if excess := len(s.scrollback) - s.maxScrollback; excess > 0 {
+		s.scrollback = append([][]rune{}, s.scrollback[excess:]...)
+	}
+}
+
+func (s *Screen) reset() {
+	s.main = newGrid(s.cols, s.rows)
+	s.alternate = nil
+	s.alternateMode = false
+	s.scrollback = nil
+	s.row, s.col = 0, 0
+	s.wrapPending = false
+	s.autoWrap = true
+	s.top, s.bottom = 0, s.rows-1
+	s.saved, s.savedMain = cursor{}, cursor{}
+}
+
+func newLine(cols int) []rune {
+	line := make([]rune, cols)
+	clearCells(line)
+	return line
+}
+
+func newGrid(cols, rows int) [][]rune {
+	grid := make([][]rune, rows)
+	for i := range grid {
+		grid[i] = newLine(cols)
+	}
+	return grid
+}
+
+func resizeGrid(grid [][]rune, cols, rows int) [][]rune {
+	ret := make([][]rune, rows)
+	for i := range ret {
+		ret[i] = newLine(cols)
+		if i < len(grid) {
+			copy(ret[i], grid[i])
+		}
+	}
+	return ret
+}
+
+func clearCells(cells []rune) {
+	for i := range cells {
+		cells[i] = ' '
+	}
+}
+
+func clamp(value, lower, upper int) int {
+	return max(lower, min(value, upper))
+}
+
+func renderLines(grid [][]rune) []string {
+	ret := make([]string, len(grid))
+	for i, line := range grid {
+		ret[i] = strings.TrimRight(string(line), " ")
+	}
+	return ret
+}
+
+func joinLines(lines []string) string {
+	end := len(lines)
+	for end > 0 && lines[end-1] == "" {
+		end--
+	}
+	return strings.Join(lines[:end], "\n")
+}
+
+// NewScreen creates a screen of cols x rows cells
+func NewScreen(cols, rows int, options ...ScreenOption) *Screen {
+	ret := &Screen{cols: max(cols, 1), rows: max(rows, 1), maxScrollback: defaultScrollback}
+	for _, option := range options {
+		option(ret)
+	}
+	ret.reset()
+	ret.parser = newParser(ret)
+	return ret
+}
+
+// Render applies terminal output to a cols x rows screen and returns what it shows at the end with scrollback,
+// e.g. the final state of progress bars and lines rewritten with carriage returns
+func Render(output string, cols, rows int) string {
+	screen := NewScreen(cols, rows)
+	_, _ = screen.WriteString(output)
+	return screen.Text()
+}
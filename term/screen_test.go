@@ -0,0 +1,101 @@
+package term
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScreen(t *testing.T) {
+	var testCases = []struct {
+		description string
+		cols, rows  int
+		output      []string
+		expect      string
+		scrollback  []string
+		row, col    int
+	}{
+		{description: "plain lines", cols: 20, rows: 5, output: []string{"one\ntwo\n"}, expect: "one\ntwo", row: 2},
+		{description: "carriage return progress", cols: 20, rows: 5, output: []string{"10%\r50%\r100%\n"}, expect: "100%", row: 1},
+		{description: "crlf", cols: 20, rows: 5, output: []string{"a\r\nb\r\n"}, expect: "a\nb", row: 2},
+		{description: "backspace", cols: 20, rows: 5, output: []string{"abc\b\bX"}, expect: "aXc", col: 2},
+		{description: "tab", cols: 20, rows: 5, output: []string{"a\tb"}, expect: "a       b", col: 9},
+		{description: "erase line", cols: 20, rows: 5, output: []string{"downloading\r\x1b[Kdone"}, expect: "done", col: 4},
+		{
+			description: "cursor up rewrites lines",
+			cols:        20, rows: 5,
+			output: []string{"layer1: 10%\nlayer2: 10%\n", "\x1b[2A\x1b[2Klayer1: done\n\x1b[2Klayer2: done\n"},
+			expect: "layer1: done\nlayer2: done", row: 2,
+		},
+		{description: "cursor position", cols: 10, rows: 3, output: []string{"\x1b[2;3Hx\x1b[1;1Hy"}, expect: "y\n  x", col: 1},
+		{description: "colors removed", cols: 20, rows: 2, output: []string{"\x1b[1;31mred\x1b[0m ok"}, expect: "red ok", col: 6},
+		{description: "osc title", cols: 20, rows: 2, output: []string{"\x1b]0;title\x07text\x1b]2;t\x1b\\!"}, expect: "text!", col: 5},
+		{description: "wrap", cols: 4, rows: 3, output: []string{"abcdef"}, expect: "abcd\nef", row: 1, col: 2},
+		{description: "no wrap before last column is overwritten", cols: 4, rows: 3, output: []string{"abcd\r\n"}, expect: "abcd", row: 1},
+		{
+			description: "scrollback",
+			cols:        10, rows: 2,
+			output:     []string{"1\n2\n3\n4"},
+			expect:     "3\n4",
+			scrollback: []string{"1", "2"},
+			row:        1, col: 1,
+		},
+		{
+			description: "alternate screen restored",
+			cols:        10, rows: 3,
+			output: []string{"$ top\n", "\x1b[?1049h\x1b[2J\x1b[Hcpu 99%", "\x1b[?1049l$ "},
+			expect: "$ top\n$", row: 1, col: 2,
+		},
+		{description: "alternate screen shown", cols: 10, rows: 3, output: []string{"$ top\n\x1b[?1049h\x1b[Hcpu 99%"}, expect: "cpu 99%", col: 7},
+		{description: "split sequence and rune", cols: 10, rows: 2, output: []string{"a\x1b[", "31mb\xc3", "\xa9"}, expect: "abé", col: 3},
+		{description: "8-bit csi", cols: 10, rows: 2, output: []string{"ab\u009b1Dc"}, expect: "ac", col: 2},
+		{description: "delete and insert chars", cols: 10, rows: 2, output: []string{"abcdef\r\x1b[2P\x1b[C\x1b[2@"}, expect: "c  def", col: 1},
+		{
+			description: "scroll region",
+			cols:        10, rows: 4,
+			output: []string{"head\n\x1b[2;3r\x1b[2;1Ha\nb\nc\x1b[r\x1b[4;1Hfoot"},
+			expect: "head\nb\nc\nfoot", row: 3, col: 4,
+		},
+		{description: "reverse index", cols: 10, rows: 3, output: []string{"a\nb\x1b[H\x1bMtop"}, expect: "top\na\nb", col: 3},
+		{description: "clear screen keeps scrollback", cols: 10, rows: 2, output: []string{"1\n2\n3\x1b[2J\x1b[Hx"}, expect: "x", scrollback: []string{"1"}, col: 1},
+		{description: "reset", cols: 10, rows: 2, output: []string{"1\n2\n3\x1bcx"}, expect: "x", col: 1},
+	}
+	for _, testCase := range testCases {
+		screen := NewScreen(testCase.cols, testCase.rows)
+		for _, output := range testCase.output {
+			_, _ = screen.WriteString(output)
+		}
+		assert.Equal(t, testCase.expect, screen.String(), testCase.description)
+		assert.Equal(t, testCase.scrollback, emptyAsNil(screen.Scrollback()), testCase.description)
+		row, col := screen.Cursor()
+		assert.Equal(t, testCase.row, row, testCase.description)
+		assert.Equal(t, testCase.col, col, testCase.description)
+	}
+}
+
+func TestScreen_Resize(t *testing.T) {
+	screen := NewScreen(10, 4)
+	_, _ = screen.WriteString("1\n2\n3\n4")
+	screen.Resize(5, 2)
+	assert.Equal(t, "3\n4", screen.String())
+	assert.Equal(t, []string{"1", "2"}, screen.Scrollback())
+	screen.Resize(8, 3)
+	_, _ = screen.WriteString("\n12345678")
+	assert.Equal(t, "3\n4\n12345678", screen.String())
+	cols, rows := screen.Size()
+	assert.Equal(t, 8, cols)
+	assert.Equal(t, 3, rows)
+}
+
+func TestRender(t *testing.T) {
+	output := "Pulling fs layer\r\n\x1b[1A\x1b[2KDownloading 50%\r\n\x1b[1A\x1b[2KPull complete\r\n"
+	assert.Equal(t, "Pull complete", Render(output, 80, 24))
+	assert.Equal(t, "1\n2\n3", Render("1\n2\n3\n", 10, 2))
+}
+
+func emptyAsNil(lines []string) []string {
+	if len(lines) == 0 {
+		return nil
+	}
+	return lines
+}
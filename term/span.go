@@ -0,0 +1,323 @@
+package term
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// colorNames are names of the 16 basic colors, SGR 30-37 and 90-97
+var colorNames = []string{
+	"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
+	"bright-black", "bright-red", "bright-green", "bright-yellow", "bright-blue", "bright-magenta", "bright-cyan", "bright-white",
+}
+
+// colorValues are xterm default values of the 16 basic colors
+var colorValues = map[string]string{
+	"black": "#000000", "red": "#cd0000", "green": "#00cd00", "yellow": "#cdcd00",
+	"blue": "#0000ee", "magenta": "#cd00cd", "cyan": "#00cdcd", "white": "#e5e5e5",
+	"bright-black": "#7f7f7f", "bright-red": "#ff0000", "bright-green": "#00ff00", "bright-yellow": "#ffff00",
+	"bright-blue": "#5c5cff", "bright-magenta": "#ff00ff", "bright-cyan": "#00ffff", "bright-white": "#ffffff",
+}
+
+type (
+	// Style represents text attributes, a color is one of the 16 basic color names (e.g. red, bright-green) or #rrggbb
+	Style struct {
+		Foreground    string `json:",omitempty"`
+		Background    string `json:",omitempty"`
+		Bold          bool   `json:",omitempty"`
+		Dim           bool   `json:",omitempty"`
+		Italic        bool   `json:",omitempty"`
+		Underline     bool   `json:",omitempty"`
+		Blink         bool   `json:",omitempty"`
+		Inverse       bool   `json:",omitempty"`
+		Hidden        bool   `json:",omitempty"`
+		Strikethrough bool   `json:",omitempty"`
+		Link          string `json:",omitempty"` // OSC 8 hyperlink target
+	}
+
+	// Span represents text sharing one style
+	Span struct {
+		Text string
+		Style
+	}
+
+	// Spans represents styled text
+	Spans []*Span
+)
+
+// apply returns style changed by SGR parameters, nil for the default style
+func (s *Style) apply(params string) *Style {
+	ret := Style{}
+	if s != nil {
+		ret = *s
+	}
+	fields := strings.Split(params, ";")
+	for i := 0; i < len(fields); i++ {
+		sub := strings.Split(fields[i], ":")
+		code, _ := strconv.Atoi(sub[0])
+		switch {
+		case code == 0:
+			ret = Style{Link: ret.Link}
+		case code == 1:
+			ret.Bold = true
+		case code == 2:
+			ret.Dim = true
+		case code == 3:
+			ret.Italic = true
+		case code == 4:
+			ret.Underline = len(sub) == 1 || sub[1] != "0"
+		case code == 5 || code == 6:
+			ret.Blink = true
+		case code == 7:
+			ret.Inverse = true
+		case code == 8:
+			ret.Hidden = true
+		case code == 9:
+			ret.Strikethrough = true
+		case code == 21:
+			ret.Underline = true
+		case code == 22:
+			ret.Bold, ret.Dim = false, false
+		case code == 23:
+			ret.Italic = false
+		case code == 24:
+			ret.Underline = false
+		case code == 25:
+			ret.Blink = false
+		case code == 27:
+			ret.Inverse = false
+		case code == 28:
+			ret.Hidden = false
+		case code == 29:
+			ret.Strikethrough = false
+		case code >= 30 && code <= 37:
+			ret.Foreground = colorNames[code-30]
+		case code == 38 || code == 48:
+			var color string
+			if len(sub) > 1 {
+				color = extendedColor(sub[1:])
+			} else {
+				var used int
+				color, used = extendedColorFields(fields[i+1:])
+				i += used
+			}
+			if code == 38 {
+				ret.Foreground = color
+			} else {
+				ret.Background = color
+			}
+		case code == 39:
+			ret.Foreground = ""
+		case code >= 40 && code <= 47:
+			ret.Background = colorNames[code-40]
+		case code == 49:
+			ret.Background = ""
+		case code >= 90 && code <= 97:
+			ret.Foreground = colorNames[code-90+8]
+		case code >= 100 && code <= 107:
+			ret.Background = colorNames[code-100+8]
+		}
+	}
+	if ret == (Style{}) {
+		return nil
+	}
+	return &ret
+}
+
+// withLink returns style with hyperlink target, an empty target ends the link
+func (s *Style) withLink(link string) *Style {
+	ret := Style{}
+	if s != nil {
+		ret = *s
+	}
+	ret.Link = link
+	if ret == (Style{}) {
+		return nil
+	}
+	return &ret
+}
+
+func (s *Style) equal(other *Style) bool {
+	if s == nil || other == nil {
+		return s == other
+	}
+	return *s == *other
+}
+
+// extendedColor returns color of colon separated sub parameters: 5:<index>, 2:<r>:<g>:<b> or 2:<colorspace>:<r>:<g>:<b>
+func extendedColor(sub []string) string {
+	values := make([]int, len(sub))
+	for i, value := range sub {
+		values[i], _ = strconv.Atoi(value)
+	}
+	switch {
+	case values[0] == 5 && len(values) > 1:
+		return indexedColor(values[1])
+	case values[0] == 2 && len(values) >= 5:
+		return rgbColor(values[2], values[3], values[4])
+	case values[0] == 2 && len(values) == 4:
+		return rgbColor(values[1], values[2], values[3])
+	}
+	return ""
+}
+
+// extendedColorFields returns color of semicolon separated parameters: 5;<index> or 2;<r>;<g>;<b>, and the number of parameters used
+func extendedColorFields(fields []string) (string, int) {
+	if len(fields) == 0 {
+		return "", 0
+	}
+	switch mode, _ := strconv.Atoi(fields[0]); {
+	case mode == 5 && len(fields) >= 2:
+		index, _ := strconv.Atoi(fields[1])
+		return indexedColor(index), 2
+	case mode == 2 && len(fields) >= 4:
+		r, _ := strconv.Atoi(fields[1])
+		g, _ := strconv.Atoi(fields[2])
+		b, _ := strconv.Atoi(fields[3])
+		return rgbColor(r, g, b), 4
+	}
+	return "", len(fields)
+}
+
+// indexedColor returns color of the xterm 256 color palette
+func indexedColor(index int) string {
+	switch {
+	case index < 0 || index > 255:
+		return ""
+	case index < 16:
+		return colorNames[index]
+	case index < 232:
+		levels := []int{0, 95, 135, 175, 215, 255}
+		index -= 16
+		return rgbColor(levels[index/36], levels[index/6%6], levels[index%6])
+	}
+	gray := 8 + (index-232)*10
+	return rgbColor(gray, gray, gray)
+}
+
+func rgbColor(r, g, b int) string {
+	return fmt.Sprintf("#%02x%02x%02x", clamp(r, 0, 255), clamp(g, 0, 255), clamp(b, 0, 255))
+}
+
+// cssColor returns #rrggbb value of a color
+func cssColor(color string) string {
+	if value, ok := colorValues[color]; ok {
+		return value
+	}
+	return color
+}
+
+// css returns inline CSS of the style
+func (s *Style) css() string {
+	foreground, background := s.Foreground, s.Background
+	if s.Inverse {
+		foreground, background = background, foreground
+		if foreground == "" {
+			foreground = "black"
+		}
+		if background == "" {
+			background = "white"
+		}
+	}
+	var declarations []string
+	if foreground != "" {
+		declarations = append(declarations, "color:"+cssColor(foreground))
+	}
+	if background != "" {
+		declarations = append(declarations, "background-color:"+cssColor(background))
+	}
+	if s.Bold {
+		declarations = append(declarations, "font-weight:bold")
+	}
+	if s.Dim {
+		declarations = append(declarations, "opacity:0.5")
+	}
+	if s.Italic {
+		declarations = append(declarations, "font-style:italic")
+	}
+	var decorations []string
+	if s.Underline {
+		decorations = append(decorations, "underline")
+	}
+	if s.Strikethrough {
+		decorations = append(decorations, "line-through")
+	}
+	if s.Blink {
+		decorations = append(decorations, "blink")
+	}
+	if len(decorations) > 0 {
+		declarations = append(declarations, "text-decoration:"+strings.Join(decorations, " "))
+	}
+	if s.Hidden {
+		declarations = append(declarations, "visibility:hidden")
+	}
+	return strings.Join(declarations, ";")
+}
+
+// append appends text, merging it with the last span of the same style
+func (s Spans) append(text string, style *Style) Spans {
+	if text == "" {
+		return s
+	}
+	if len(s) > 0 {
+		if last := s[len(s)-1]; (style == nil && last.Style == Style{}) || (style != nil && last.Style == *style) {
+			last.Text += text
+			return s
+		}
+	}
+	span := &Span{Text: text}
+	if style != nil {
+		span.Style = *style
+	}
+	return append(s, span)
+}
+
+// String returns text without styles
+func (s Spans) String() string {
+	builder := strings.Builder{}
+	for _, span := range s {
+		builder.WriteString(span.Text)
+	}
+	return builder.String()
+}
+
+// HTML returns text as HTML with inline styles, hyperlinks are rendered as anchors; the result is meant for a pre element
+func (s Spans) HTML() string {
+	builder := strings.Builder{}
+	for _, span := range s {
+		text := html.EscapeString(span.Text)
+		if css := span.css(); css != "" {
+			text = `<span style="` + css + `">` + text + `</span>`
+		}
+		if span.Link != "" {
+			text = `<a href="` + html.EscapeString(span.Link) + `">` + text + `</a>`
+		}
+		builder.WriteString(text)
+	}
+	return builder.String()
+}
+
+// JSON returns spans as JSON array of objects with Text and non default style attributes
+func (s Spans) JSON() ([]byte, error) {
+	if s == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(s)
+}
+
+// Parse returns styled spans of terminal output: SGR attributes and 16, 256 and true colors, and OSC 8 hyperlinks set span styles;
+// other sequences are removed and carriage returns, backspaces and line erasing are applied as by Normalize
+func Parse(text string) Spans {
+	aNormalizer := &normalizer{styled: true}
+	aParser := newParser(aNormalizer)
+	aParser.write([]byte(text))
+	if len(aParser.pending) > 0 {
+		aNormalizer.print(utf8.RuneError)
+	}
+	aNormalizer.flush()
+	return aNormalizer.spans
+}
@@ -0,0 +1,81 @@
+package term
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	var testCases = []struct {
+		description string
+		input       string
+		expect      Spans
+	}{
+		{description: "plain", input: "plain text", expect: Spans{{Text: "plain text"}}},
+		{
+			description: "basic colors",
+			input:       "\x1b[31mFAIL\x1b[0m test\n\x1b[1;32mPASS\x1b[m",
+			expect: Spans{
+				{Text: "FAIL", Style: Style{Foreground: "red"}},
+				{Text: " test\n"},
+				{Text: "PASS", Style: Style{Foreground: "green", Bold: true}},
+			},
+		},
+		{
+			description: "bright, 256 and true colors",
+			input:       "\x1b[91;104ma\x1b[38;5;196;48;5;244mb\x1b[38:2::0:128:255;48;2;1;2;3mc\x1b[38;5;9md",
+			expect: Spans{
+				{Text: "a", Style: Style{Foreground: "bright-red", Background: "bright-blue"}},
+				{Text: "b", Style: Style{Foreground: "#ff0000", Background: "#808080"}},
+				{Text: "c", Style: Style{Foreground: "#0080ff", Background: "#010203"}},
+				{Text: "d", Style: Style{Foreground: "bright-red", Background: "#010203"}},
+			},
+		},
+		{
+			description: "attributes on and off",
+			input:       "\x1b[1;3;4;9mx\x1b[22;23mx\x1b[24;29;7mx\x1b[27;39mx",
+			expect: Spans{
+				{Text: "x", Style: Style{Bold: true, Italic: true, Underline: true, Strikethrough: true}},
+				{Text: "x", Style: Style{Underline: true, Strikethrough: true}},
+				{Text: "x", Style: Style{Inverse: true}},
+				{Text: "x"},
+			},
+		},
+		{
+			description: "hyperlink",
+			input:       "see \x1b]8;;https://viant.io\x1b\\\x1b[4mdocs\x1b[24m\x1b]8;;\x1b\\ now",
+			expect: Spans{
+				{Text: "see "},
+				{Text: "docs", Style: Style{Underline: true, Link: "https://viant.io"}},
+				{Text: " now"},
+			},
+		},
+		{
+			description: "carriage return keeps styles of overwritten cells",
+			input:       "\x1b[33m50%\x1b[0m\r\x1b[32m100%\x1b[0m done",
+			expect:      Spans{{Text: "100%", Style: Style{Foreground: "green"}}, {Text: " done"}},
+		},
+		{description: "other sequences removed", input: "\x1b]0;title\x07\x1b[?2004ha\x1b[2Kb", expect: Spans{{Text: " b"}}},
+		{description: "empty", input: "", expect: nil},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expect, Parse(testCase.input), testCase.description)
+	}
+}
+
+func TestSpans_HTML(t *testing.T) {
+	spans := Parse("\x1b[1;31m<error>\x1b[0m & \x1b]8;;https://viant.io/?a=1&b=2\x07\x1b[7mlink\x1b]8;;\x07")
+	assert.Equal(t, `<span style="color:#cd0000;font-weight:bold">&lt;error&gt;</span> &amp; `+
+		`<a href="https://viant.io/?a=1&amp;b=2"><span style="color:#000000;background-color:#e5e5e5">link</span></a>`, spans.HTML())
+	assert.Equal(t, "<error> & link", spans.String())
+}
+
+func TestSpans_JSON(t *testing.T) {
+	data, err := Parse("\x1b[31mred\x1b[0m plain").JSON()
+	assert.Nil(t, err)
+	assert.JSONEq(t, `[{"Text":"red","Foreground":"red"},{"Text":" plain"}]`, string(data))
+	data, err = Parse("").JSON()
+	assert.Nil(t, err)
+	assert.Equal(t, "[]", string(data))
+}